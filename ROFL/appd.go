@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Submitter sends HealthTrust writes. txService signs locally with a key we
+// hold; appdSubmitter has the ROFL app daemon sign with the app's own key,
+// which is what lets roflEnsureAuthorizedOrigin checks pass on chain.
+type Submitter interface {
+	Submit(ctx context.Context, method string, args ...interface{}) error
+}
+
+// newSubmitter picks the transaction backend from TX_BACKEND: "appd", as
+// deployed in ROFL, or "local" (the default), which signs with the key
+// selected by newSigner.
+func newSubmitter(ctx context.Context) (Submitter, error) {
+	switch backend := envOr("TX_BACKEND", "local"); backend {
+	case "appd":
+		return newAppdSubmitter(envOr("APPD_SOCKET", "/run/rofl-appd.sock"), uint64(envInt("APPD_GAS_LIMIT", 300_000)))
+	case "local":
+		signer, err := newSigner(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return newTxService(ctx, signer)
+	default:
+		return nil, fmt.Errorf("unknown TX_BACKEND %q", backend)
+	}
+}
+
+const (
+	appdBaseURL          = "http://localhost"
+	appdSignSubmitPath   = "/rofl/v1/tx/sign-submit"
+	appdKeysGeneratePath = "/rofl/v1/keys/generate"
+)
+
+// appdSubmitter posts transactions to rofl-appd over its unix socket. The
+// daemon picks the nonce and fees and waits for the result.
+type appdSubmitter struct {
+	http     *http.Client
+	abi      *abi.ABI
+	gasLimit uint64
+}
+
+func newAppdSubmitter(socket string, gasLimit uint64) (*appdSubmitter, error) {
+	parsed, err := HealthTrustMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return &appdSubmitter{
+		http:     newAppdClient(socket),
+		abi:      parsed,
+		gasLimit: gasLimit,
+	}, nil
+}
+
+// newAppdClient returns an HTTP client that talks to rofl-appd on socket,
+// whatever host the request URL names.
+func newAppdClient(socket string) *http.Client {
+	return &http.Client{
+		// sign-submit returns once the transaction is in a block
+		Timeout: 2 * time.Minute,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+}
+
+type appdSignSubmitRequest struct {
+	Tx struct {
+		Kind string      `json:"kind"`
+		Data appdEthCall `json:"data"`
+	} `json:"tx"`
+	Encrypt bool `json:"encrypt"`
+}
+
+type appdEthCall struct {
+	GasLimit uint64 `json:"gas_limit"`
+	To       string `json:"to"`    // hex, no 0x prefix
+	Value    uint64 `json:"value"` // wei
+	Data     string `json:"data"`  // hex, no 0x prefix
+}
+
+type appdSignSubmitResponse struct {
+	Data string `json:"data"` // hex CBOR-encoded call result
+}
+
+func (a *appdSubmitter) Submit(ctx context.Context, method string, args ...interface{}) error {
+	data, err := a.abi.Pack(method, args...)
+	if err != nil {
+		return fmt.Errorf("failed to pack %s: %v", method, err)
+	}
+
+	var body appdSignSubmitRequest
+	body.Tx.Kind = "eth"
+	body.Tx.Data = appdEthCall{
+		GasLimit: a.gasLimit,
+		To:       strings.TrimPrefix(CONTRACT_ADDR.Hex(), "0x"),
+		Value:    0,
+		Data:     hex.EncodeToString(data),
+	}
+
+	slog.Info("Submitting transaction through rofl-appd", "method", method)
+	var out appdSignSubmitResponse
+	if err := appdCall(ctx, a.http, appdSignSubmitPath, body, &out); err != nil {
+		return err
+	}
+	if err := checkCallResult(out.Data); err != nil {
+		return fmt.Errorf("%s failed: %v", method, err)
+	}
+	slog.Info("Transaction submitted through rofl-appd", "method", method)
+	return nil
+}
+
+// appdCall POSTs in as JSON to the rofl-appd endpoint at path and decodes
+// the JSON response into out.
+func appdCall(ctx context.Context, cli *http.Client, path string, in, out interface{}) error {
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, appdBaseURL+path, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read rofl-appd response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s", &httpStatusError{URL: path, Status: resp.StatusCode}, strings.TrimSpace(string(respBody)))
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse rofl-appd response: %v", err)
+	}
+	return nil
+}
+
+type appdKeyRequest struct {
+	KeyID string `json:"key_id"`
+	Kind  string `json:"kind"`
+}
+
+type appdKeyResponse struct {
+	Key string `json:"key"` // hex
+}
+
+// appdGenerateKey returns the app's secp256k1 key named keyID. rofl-appd
+// derives it inside the TEE from the app identity, so the same id always
+// yields the same key on every replica and nothing has to be stored.
+func appdGenerateKey(ctx context.Context, socket, keyID string) (*ecdsa.PrivateKey, error) {
+	var out appdKeyResponse
+	if err := appdCall(ctx, newAppdClient(socket), appdKeysGeneratePath, appdKeyRequest{KeyID: keyID, Kind: "secp256k1"}, &out); err != nil {
+		return nil, err
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(out.Key, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("rofl-appd returned an invalid key: %v", err)
+	}
+	return key, nil
+}
+
+// checkCallResult decodes the CBOR call result returned by sign-submit:
+// {"ok": ...} on success, {"fail": {"module", "code", "message"}} when the
+// transaction reverted.
+func checkCallResult(hexData string) error {
+	raw, err := hex.DecodeString(strings.TrimPrefix(hexData, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid call result %q: %v", hexData, err)
+	}
+	v, _, err := decodeCBOR(raw)
+	if err != nil {
+		return fmt.Errorf("invalid call result %x: %v", raw, err)
+	}
+	result, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected call result %x", raw)
+	}
+	if _, ok := result["ok"]; ok {
+		return nil
+	}
+	if fail, ok := result["fail"].(map[string]interface{}); ok {
+		return fmt.Errorf("module %v code %v: %v", fail["module"], fail["code"], fail["message"])
+	}
+	return fmt.Errorf("unexpected call result %x", raw)
+}
+
+// decodeCBOR decodes the subset of CBOR that call results use: unsigned
+// integers, byte and text strings, arrays and maps with text keys.
+func decodeCBOR(b []byte) (v interface{}, rest []byte, err error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("unexpected end of data")
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(b) < size {
+			return nil, nil, errors.New("unexpected end of data")
+		}
+		for _, c := range b[:size] {
+			n = n<<8 | uint64(c)
+		}
+		b = b[size:]
+	default:
+		return nil, nil, fmt.Errorf("unsupported additional info %d", info)
+	}
+
+	switch major {
+	case 0: // unsigned integer
+		return n, b, nil
+	case 2, 3: // byte string, text string
+		if uint64(len(b)) < n {
+			return nil, nil, errors.New("unexpected end of data")
+		}
+		if major == 3 {
+			return string(b[:n]), b[n:], nil
+		}
+		return b[:n], b[n:], nil
+	case 4: // array
+		arr := make([]interface{}, 0, min(n, 64))
+		for i := uint64(0); i < n; i++ {
+			var item interface{}
+			if item, b, err = decodeCBOR(b); err != nil {
+				return nil, nil, err
+			}
+			arr = append(arr, item)
+		}
+		return arr, b, nil
+	case 5: // map
+		m := make(map[string]interface{}, min(n, 64))
+		for i := uint64(0); i < n; i++ {
+			var key, val interface{}
+			if key, b, err = decodeCBOR(b); err != nil {
+				return nil, nil, err
+			}
+			if val, b, err = decodeCBOR(b); err != nil {
+				return nil, nil, err
+			}
+			m[fmt.Sprint(key)] = val
+		}
+		return m, b, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported major type %d", major)
+	}
+}
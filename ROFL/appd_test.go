@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LeonardoRyuta/HealthTrust/internal/fakeappd"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// serveAppd serves handler on a unix socket the way rofl-appd does and
+// returns the socket path.
+func serveAppd(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "appd.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = ln
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return socket
+}
+
+// stubChain records what fakeappd was asked to submit and answers with a
+// receipt of the given status.
+type stubChain struct {
+	status   uint64
+	to       common.Address
+	gasLimit uint64
+	value    *big.Int
+	data     []byte
+}
+
+func (c *stubChain) Submit(_ context.Context, to common.Address, gasLimit uint64, value *big.Int, data []byte) (*types.Receipt, error) {
+	c.to, c.gasLimit, c.value, c.data = to, gasLimit, value, data
+	return &types.Receipt{Status: c.status}, nil
+}
+
+func TestAppdSubmitter(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		status  uint64
+		wantErr string
+	}{
+		{"ok", types.ReceiptStatusSuccessful, ""},
+		{"fail", types.ReceiptStatusFailed, "completeOrder failed: module evm code 8: reverted"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chain := &stubChain{status: tc.status}
+			socket := serveAppd(t, fakeappd.NewHandler(chain))
+
+			sub, err := newAppdSubmitter(socket, 123_456)
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := []interface{}{big.NewInt(7), big.NewInt(3), "bafyresult"}
+			err = sub.Submit(context.Background(), "completeOrder", args...)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("Submit: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || err.Error() != tc.wantErr) {
+				t.Fatalf("Submit: got error %v, want %q", err, tc.wantErr)
+			}
+
+			if chain.to != CONTRACT_ADDR || chain.gasLimit != 123_456 || chain.value.Sign() != 0 {
+				t.Errorf("submitted to %s, gas limit %d, value %s", chain.to.Hex(), chain.gasLimit, chain.value)
+			}
+			method, err := sub.abi.MethodById(chain.data)
+			if err != nil || method.Name != "completeOrder" {
+				t.Fatalf("data calls %v (%v), want completeOrder", method, err)
+			}
+			unpacked, err := method.Inputs.Unpack(chain.data[4:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(unpacked, args) {
+				t.Errorf("arguments %v, want %v", unpacked, args)
+			}
+		})
+	}
+}
+
+func TestAppdSubmitterHTTPError(t *testing.T) {
+	socket := serveAppd(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of gas", http.StatusInternalServerError)
+	}))
+	sub, err := newAppdSubmitter(socket, 100_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sub.Submit(context.Background(), "completeOrder", big.NewInt(1), big.NewInt(1), "cid")
+	if err == nil || !strings.Contains(err.Error(), "out of gas") {
+		t.Fatalf("got %v, want the daemon's error", err)
+	}
+}
+
+func TestAppdGenerateKey(t *testing.T) {
+	socket := serveAppd(t, fakeappd.NewHandler(nil))
+	ctx := context.Background()
+
+	a, err := appdGenerateKey(ctx, socket, appdKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := appdGenerateKey(ctx, socket, appdKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := appdGenerateKey(ctx, socket, appdKeyID+"-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Equal(again) {
+		t.Error("same key id gave different keys")
+	}
+	if a.Equal(other) {
+		t.Error("different key ids gave the same key")
+	}
+}
+
+func TestCheckCallResult(t *testing.T) {
+	for _, tc := range []struct {
+		data, wantErr string
+	}{
+		{fakeappd.CallOK, ""},
+		{"0x" + fakeappd.CallOK, ""},
+		{fakeappd.CallReverted, "module evm code 8: reverted"},
+		{"a0", "unexpected call result a0"}, // {}
+		{"a1626f", "invalid call result a1626f: unexpected end of data"},
+		{"zz", `invalid call result "zz": encoding/hex: invalid byte: U+007A 'z'`},
+	} {
+		err := checkCallResult(tc.data)
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("%s: %v", tc.data, err)
+		}
+		if tc.wantErr != "" && (err == nil || err.Error() != tc.wantErr) {
+			t.Errorf("%s: got %v, want %q", tc.data, err, tc.wantErr)
+		}
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	for _, tc := range []struct {
+		hex  string
+		want interface{}
+	}{
+		{"00", uint64(0)},
+		{"17", uint64(23)},
+		{"1818", uint64(24)},
+		{"1a000f4240", uint64(1_000_000)},
+		{"43010203", []byte{1, 2, 3}},
+		{"6161", "a"},
+		{"820107", []interface{}{uint64(1), uint64(7)}},
+		{"a16161820102", map[string]interface{}{"a": []interface{}{uint64(1), uint64(2)}}},
+	} {
+		raw, _ := hex.DecodeString(tc.hex)
+		got, rest, err := decodeCBOR(raw)
+		if err != nil || len(rest) != 0 || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %#v, rest %x, err %v; want %#v", tc.hex, got, rest, err, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"", "18", "62ff", "8201", "f6", "1c"} {
+		raw, _ := hex.DecodeString(bad)
+		if _, _, err := decodeCBOR(raw); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// runCommand runs the maintenance command named by args instead of the
+// worker, e.g. `./rofl-service keys audit`.
+func runCommand(ctx context.Context, args []string) error {
+	switch cmd := strings.Join(args, " "); cmd {
+	case "keys audit":
+		var err error
+		keys, err = openKeyring(ctx)
+		if err != nil {
+			return err
+		}
+		flagged, err := auditKeys(ctx, os.Stdout)
+		if err != nil {
+			return err
+		}
+		if flagged > 0 {
+			return fmt.Errorf("%d dataset(s) are not encrypted to the current key %s", flagged, keys.Current())
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q (available: keys audit)", cmd)
+	}
+}
+
+// auditKeys lists the key every dataset on the contract is encrypted to and
+// returns how many are bound to a retired or unknown key, or could not be
+// read. The worker cannot re-encrypt them itself, since only a dataset's
+// provider can replace its IPFS hash; the report tells them which to upload
+// again for the current key.
+func auditKeys(ctx context.Context, w io.Writer) (flagged int, err error) {
+	ht, cli, err := dialContract()
+	if err != nil {
+		return 0, err
+	}
+	defer cli.Close()
+
+	count, err := ht.GetDatasetCount(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get dataset count: %v", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATASET\tKEY\tSTATUS")
+	for id := new(big.Int); id.Cmp(count) < 0; id.Add(id, big.NewInt(1)) {
+		keyID, status := auditDataset(id)
+		if status != "current" {
+			flagged++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", id, keyID, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return 0, err
+	}
+	return flagged, nil
+}
+
+// auditDataset returns the key dataset id is encrypted to and whether that
+// key is current, retired or unknown. Streaming envelopes are identified
+// from their header alone; legacy datasets without a key id are identified
+// by trying each key.
+func auditDataset(id *big.Int) (keyID, status string) {
+	res, err := getDataHash(id)
+	if err != nil {
+		return "-", "unreadable: " + err.Error()
+	}
+	body, err := openIPFS(res.IPFSHash)
+	if err != nil {
+		return "-", "unreadable: " + err.Error()
+	}
+	defer body.Close()
+
+	meta, _, err := decryptStream(body)
+	keyID = meta.KeyID
+	if keyID == "" {
+		keyID = "-"
+	}
+	suffix := ""
+	if meta.Version == envelopeLegacy {
+		suffix = " (legacy, no key id)"
+	}
+	switch {
+	case err != nil && meta.KeyID != "" && !keys.Retired(meta.KeyID) && meta.KeyID != keys.Current():
+		return keyID, "unknown key" + suffix
+	case err != nil:
+		return keyID, "unreadable: " + err.Error()
+	case meta.KeyID == keys.Current():
+		return keyID, "current" + suffix
+	default:
+		return keyID, "retired" + suffix
+	}
+}
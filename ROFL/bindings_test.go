@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/abigen"
+)
+
+// healthTrustArtifact is written by `npx hardhat compile` in ../Contracts.
+const healthTrustArtifact = "../Contracts/artifacts/contracts/HealthTrust_contract.sol/HealthTrust.json"
+
+// TestBindingsMatchABI regenerates each binding the way go:generate in
+// contract.go does and fails if the committed file differs.
+func TestBindingsMatchABI(t *testing.T) {
+	for _, b := range []struct{ typ, abi, out string }{
+		{"HealthTrust", "HealthTrust.abi.json", "healthtrust.go"},
+		{"ERC20", "ERC20.abi.json", "erc20.go"},
+	} {
+		abiJSON, err := os.ReadFile(b.abi)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := abigen.Bind([]string{b.typ}, []string{string(abiJSON)}, []string{""}, []map[string]string{nil},
+			"main", map[string]string{}, map[string]string{})
+		if err != nil {
+			t.Fatalf("%s: %v", b.abi, err)
+		}
+		got, err := os.ReadFile(b.out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s is stale; run 'make bindings'", b.out)
+		}
+	}
+}
+
+// TestBindingsMatchContract compares HealthTrust.abi.json with the ABI of the
+// compiled Solidity source. It needs the Hardhat artifact, which CI builds
+// before running the tests.
+func TestBindingsMatchContract(t *testing.T) {
+	raw, err := os.ReadFile(healthTrustArtifact)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skip("no Hardhat artifact; run `npx hardhat compile` in ../Contracts")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var artifact struct {
+		ABI any `json:"abi"`
+	}
+	if err := json.Unmarshal(raw, &artifact); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err = os.ReadFile("HealthTrust.abi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var committed any
+	if err := json.Unmarshal(raw, &committed); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(committed, artifact.ABI) {
+		t.Error("HealthTrust.abi.json does not match HealthTrust_contract.sol; run 'make bindings'")
+	}
+}
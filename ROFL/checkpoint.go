@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// checkpoint records the last block whose OrderCreated logs have all been
+// handed off, so a restarted worker can replay whatever it missed.
+type checkpoint struct {
+	path string
+}
+
+type checkpointFile struct {
+	Block uint64 `json:"block"`
+}
+
+func newCheckpoint(dir string) *checkpoint {
+	return &checkpoint{path: filepath.Join(dir, "checkpoint.json")}
+}
+
+// Load returns the stored block. ok is false when no checkpoint exists yet.
+func (c *checkpoint) Load() (block uint64, ok bool, err error) {
+	raw, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+
+	var f checkpointFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return 0, false, fmt.Errorf("failed to parse checkpoint %s: %v", c.path, err)
+	}
+	return f.Block, true, nil
+}
+
+// Save atomically replaces the stored block.
+func (c *checkpoint) Save(block uint64) error {
+	raw, err := json.Marshal(checkpointFile{Block: block})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, raw)
+}
+
+// writeFileAtomic writes data next to path and renames it into place, so a
+// crash never leaves a half-written file behind.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", tmp, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %v", tmp, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync %s: %v", tmp, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", tmp, err)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,48 @@
+package main
+
+//go:generate go run github.com/ethereum/go-ethereum/cmd/abigen --abi HealthTrust.abi.json --pkg main --type HealthTrust --out healthtrust.go
+//go:generate go run github.com/ethereum/go-ethereum/cmd/abigen --abi ERC20.abi.json --pkg main --type ERC20 --out erc20.go
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// orderEvents parses HealthTrust logs. Parsing needs no backend.
+var orderEvents, _ = NewHealthTrustFilterer(CONTRACT_ADDR, nil)
+
+// orderCreatedTopic returns the topic of the OrderCreated event.
+func orderCreatedTopic() (common.Hash, error) {
+	parsed, err := HealthTrustMetaData.GetAbi()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	ev, ok := parsed.Events["OrderCreated"]
+	if !ok {
+		return common.Hash{}, fmt.Errorf("OrderCreated missing from HealthTrust ABI")
+	}
+	return ev.ID, nil
+}
+
+// dialContract connects to RPC_URL and binds the HealthTrust contract. The
+// caller must Close the returned client.
+func dialContract() (*HealthTrust, *ethclient.Client, error) {
+	cli, err := ethclient.Dial(RPC_URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	ht, err := NewHealthTrust(CONTRACT_ADDR, cli)
+	if err != nil {
+		cli.Close()
+		return nil, nil, err
+	}
+	return ht, cli, nil
+}
+
+// parseOrderCreated decodes an OrderCreated log.
+func parseOrderCreated(vLog types.Log) (*HealthTrustOrderCreated, error) {
+	return orderEvents.ParseOrderCreated(vLog)
+}
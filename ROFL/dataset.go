@@ -3,33 +3,41 @@ package main
 import (
 	"context"
 	"math/big"
-	"strings"
 
-	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 )
 
-func getDataHash(id uint64) (DataResponse, error) {
-	cli, err := ethclient.Dial(RPC_URL) // JSON‑RPC call :contentReference[oaicite:3]{index=3}
+func getDataHash(id *big.Int) (DataResponse, error) {
+	ht, cli, err := dialContract()
 	if err != nil {
 		return DataResponse{}, err
 	}
-
-	escAbi, _ := abi.JSON(strings.NewReader(ABI_JSON))
-	input, _ := escAbi.Pack("getDatasetHash", big.NewInt(int64(id)))
+	defer cli.Close()
 
 	// Note: Sapphire requires ECIES envelope, but JSON‑RPC GET is fine for eth_call.
-	msg := ethereum.CallMsg{To: &CONTRACT_ADDR, Data: input}
-	out, err := cli.CallContract(context.Background(), msg, nil) // eth_call :contentReference[oaicite:4]{index=4}
+	hash, err := ht.GetDatasetHash(&bind.CallOpts{Context: context.Background()}, id)
 	if err != nil {
 		return DataResponse{}, err
 	}
+	return DataResponse{IPFSHash: hash}, nil
+}
 
-	var data DataResponse
-	err = escAbi.UnpackIntoInterface(&data, "getDatasetHash", out)
+// getDatasetMetrics returns the metrics the dataset declares on chain. It is
+// empty for datasets registered before healthMetricTypes existed.
+func getDatasetMetrics(id *big.Int) ([]metricDef, error) {
+	ht, cli, err := dialContract()
 	if err != nil {
-		return DataResponse{}, err
+		return nil, err
+	}
+	defer cli.Close()
+
+	ds, err := ht.GetDataset(&bind.CallOpts{Context: context.Background()}, id)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := declaredMetrics(ds.HealthMetricTypes)
+	if err != nil {
+		return nil, permanentErr("get dataset metrics", err)
 	}
-	return data, nil
+	return defs, nil
 }
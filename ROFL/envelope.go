@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+)
+
+// Envelope versions. Version 0 is a bare ECIES ciphertext, version 1 the
+// keyEnvelope JSON that names its key; both are decrypted in memory.
+// Version 2 streams: an ECIES-wrapped AES-256 key followed by the payload in
+// AES-GCM chunks, so a dataset is never held in memory whole.
+const (
+	envelopeLegacy = 0
+	envelopeKeyed  = 1
+	envelopeStream = 2
+)
+
+// A version 2 envelope is laid out as
+//
+//	"HTE2" | uint32 header length | header JSON | chunk...
+//
+// and each chunk as
+//
+//	uint32 sealed length, top bit set on the last chunk | AES-GCM output
+//
+// Chunk i is sealed under the nonce noncePrefix | uint32 i | last (0 or 1)
+// with the header JSON as additional data, following the STREAM
+// construction: chunks cannot be reordered, dropped or cut off at the end,
+// and the header cannot be swapped, without decryption failing.
+const streamMagic = "HTE2"
+
+const (
+	streamKeySize     = 32
+	streamPrefixSize  = 7
+	streamFinalBit    = 1 << 31
+	maxStreamHeader   = 64 << 10
+	maxStreamChunk    = 1 << 20
+	streamChunkHeader = 4
+)
+
+// streamHeader describes a version 2 envelope.
+type streamHeader struct {
+	Version     int           `json:"version"`
+	KeyID       string        `json:"keyId"`
+	WrappedKey  hexutil.Bytes `json:"wrappedKey"`  // ECIES ciphertext of the AES-256 key
+	NoncePrefix hexutil.Bytes `json:"noncePrefix"` // 7 random bytes
+	ChunkSize   int           `json:"chunkSize"`   // max plaintext bytes per chunk
+	// ContentType is the format of the plaintext, e.g. "application/json".
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// envelopeMeta is what decryptStream learned about an envelope.
+type envelopeMeta struct {
+	Version     int
+	KeyID       string // key that opened it, or that it names
+	ContentType string // empty unless the envelope records it
+}
+
+// decryptStream decrypts the envelope read from r. Version 2 envelopes are
+// decrypted as the returned reader is consumed; older ones are read and
+// decrypted in full first. meta is filled in as far as the envelope was
+// understood, even on error. Malformed or unauthentic data is reported as a
+// permanent error, read failures as they are.
+func decryptStream(r io.Reader) (meta envelopeMeta, plaintext io.Reader, err error) {
+	if keys == nil {
+		return meta, nil, errors.New("keyring not initialized")
+	}
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(streamMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return meta, nil, err
+	}
+	if string(magic) != streamMagic {
+		return decryptLegacy(br)
+	}
+
+	h, aad, err := readStreamHeader(br)
+	if err != nil {
+		return meta, nil, err
+	}
+	meta = envelopeMeta{Version: envelopeStream, KeyID: h.KeyID, ContentType: h.ContentType}
+	key, _, err := keys.Decrypt(h.KeyID, h.WrappedKey)
+	if err != nil {
+		return meta, nil, permanentErr("decrypt dataset", err)
+	}
+	if len(key) != streamKeySize {
+		return meta, nil, permanentErr("decrypt dataset", fmt.Errorf("wrapped key is %d bytes, want %d", len(key), streamKeySize))
+	}
+	warnRetired(h.KeyID)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return meta, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return meta, nil, err
+	}
+	return meta, &chunkReader{r: br, aead: aead, prefix: h.NoncePrefix, aad: aad, maxSealed: h.ChunkSize + aead.Overhead()}, nil
+}
+
+func readStreamHeader(br *bufio.Reader) (streamHeader, []byte, error) {
+	var h streamHeader
+	var pre [len(streamMagic) + 4]byte
+	if _, err := io.ReadFull(br, pre[:]); err != nil {
+		return h, nil, err
+	}
+	n := binary.BigEndian.Uint32(pre[len(streamMagic):])
+	if n > maxStreamHeader {
+		return h, nil, permanentErr("decrypt dataset", fmt.Errorf("envelope header of %d bytes exceeds %d", n, maxStreamHeader))
+	}
+	aad := make([]byte, n)
+	if _, err := io.ReadFull(br, aad); err != nil {
+		return h, nil, err
+	}
+	if err := json.Unmarshal(aad, &h); err != nil {
+		return h, nil, permanentErr("decrypt dataset", fmt.Errorf("invalid envelope header: %v", err))
+	}
+	var err error
+	switch {
+	case h.Version != envelopeStream:
+		err = fmt.Errorf("unsupported envelope version %d", h.Version)
+	case h.KeyID == "":
+		err = errors.New("envelope header has no keyId")
+	case len(h.NoncePrefix) != streamPrefixSize:
+		err = fmt.Errorf("nonce prefix is %d bytes, want %d", len(h.NoncePrefix), streamPrefixSize)
+	case h.ChunkSize <= 0 || h.ChunkSize > maxStreamChunk:
+		err = fmt.Errorf("chunk size %d out of range", h.ChunkSize)
+	}
+	if err != nil {
+		return h, nil, permanentErr("decrypt dataset", err)
+	}
+	return h, aad, nil
+}
+
+// decryptLegacy reads a version 0 or 1 envelope whole and decrypts it.
+func decryptLegacy(r io.Reader) (envelopeMeta, io.Reader, error) {
+	meta := envelopeMeta{Version: envelopeLegacy}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return meta, nil, err
+	}
+	id, ciphertext, err := openEnvelope(raw)
+	if err != nil {
+		return meta, nil, permanentErr("decrypt dataset", err)
+	}
+	if id != "" {
+		meta.Version, meta.KeyID = envelopeKeyed, id
+	}
+	plaintext, usedID, err := keys.Decrypt(id, ciphertext)
+	if err != nil {
+		return meta, nil, permanentErr("decrypt dataset", err)
+	}
+	meta.KeyID = usedID
+	warnRetired(usedID)
+	return meta, bytes.NewReader(plaintext), nil
+}
+
+func warnRetired(id string) {
+	if keys.Retired(id) {
+		slog.Warn("Dataset is encrypted to a retired key", "keyId", id)
+	}
+}
+
+// chunkReader decrypts version 2 chunks one at a time as they are read.
+type chunkReader struct {
+	r         *bufio.Reader
+	aead      cipher.AEAD
+	prefix    []byte
+	aad       []byte
+	maxSealed int
+
+	counter uint32
+	buf     []byte // decrypted bytes not yet returned
+	sealed  []byte // reused for chunk ciphertext
+	final   bool   // the last chunk has been decrypted
+	err     error
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		if c.final {
+			// Anything after the last chunk was not written by the sealer.
+			if _, err := c.r.ReadByte(); err == nil {
+				c.err = permanentErr("decrypt dataset", errors.New("data after the last chunk"))
+			} else {
+				c.err = io.EOF
+			}
+			continue
+		}
+		c.err = c.next()
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// next reads and decrypts the next chunk into buf.
+func (c *chunkReader) next() error {
+	var lenBuf [streamChunkHeader]byte
+	if _, err := io.ReadFull(c.r, lenBuf[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			// A clean end of stream before the last chunk is a cut-off
+			// download or a truncated file.
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	v := binary.BigEndian.Uint32(lenBuf[:])
+	final, n := v&streamFinalBit != 0, int(v&^streamFinalBit)
+	if n < c.aead.Overhead() || n > c.maxSealed {
+		return permanentErr("decrypt dataset", fmt.Errorf("chunk %d has invalid length %d", c.counter, n))
+	}
+
+	if cap(c.sealed) < n {
+		c.sealed = make([]byte, n)
+	}
+	c.sealed = c.sealed[:n]
+	if _, err := io.ReadFull(c.r, c.sealed); err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	plain, err := c.aead.Open(c.sealed[:0], streamNonce(c.prefix, c.counter, final), c.sealed, c.aad)
+	if err != nil {
+		return permanentErr("decrypt dataset", fmt.Errorf("chunk %d failed authentication", c.counter))
+	}
+	if c.counter == ^uint32(0) {
+		return permanentErr("decrypt dataset", errors.New("too many chunks"))
+	}
+	c.counter++
+	c.buf, c.final = plain, final
+	return nil
+}
+
+func streamNonce(prefix []byte, counter uint32, final bool) []byte {
+	nonce := make([]byte, 0, streamPrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if final {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// encryptStream returns a writer that seals what is written to it into a
+// version 2 envelope on w, encrypted to pub. It is the reference producer
+// for the format; Close must be called to write the last chunk.
+func encryptStream(w io.Writer, pub *ecdsa.PublicKey, contentType string, chunkSize int) (io.WriteCloser, error) {
+	if chunkSize <= 0 || chunkSize > maxStreamChunk {
+		return nil, fmt.Errorf("chunk size %d out of range", chunkSize)
+	}
+	key := make([]byte, streamKeySize)
+	prefix := make([]byte, streamPrefixSize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	wrapped, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(pub), key, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap key: %v", err)
+	}
+
+	aad, err := json.Marshal(streamHeader{
+		Version:     envelopeStream,
+		KeyID:       keyID(pub),
+		WrappedKey:  wrapped,
+		NoncePrefix: prefix,
+		ChunkSize:   chunkSize,
+		ContentType: contentType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pre := binary.BigEndian.AppendUint32([]byte(streamMagic), uint32(len(aad)))
+	if _, err := w.Write(append(pre, aad...)); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &chunkWriter{w: w, aead: aead, prefix: prefix, aad: aad, size: chunkSize}, nil
+}
+
+// chunkWriter holds back up to one chunk, since a chunk can only be sealed
+// once it is known whether more data follows.
+type chunkWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	aad     []byte
+	size    int
+	counter uint32
+	buf     []byte
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	for len(c.buf) > c.size {
+		if err := c.seal(c.buf[:c.size], false); err != nil {
+			return 0, err
+		}
+		c.buf = c.buf[c.size:]
+	}
+	return len(p), nil
+}
+
+// Close seals the remaining data as the last chunk.
+func (c *chunkWriter) Close() error {
+	return c.seal(c.buf, true)
+}
+
+func (c *chunkWriter) seal(plain []byte, final bool) error {
+	sealed := c.aead.Seal(nil, streamNonce(c.prefix, c.counter, final), plain, c.aad)
+	v := uint32(len(sealed))
+	if final {
+		v |= streamFinalBit
+	}
+	if _, err := c.w.Write(binary.BigEndian.AppendUint32(nil, v)); err != nil {
+		return err
+	}
+	if _, err := c.w.Write(sealed); err != nil {
+		return err
+	}
+	c.counter++
+	return nil
+}
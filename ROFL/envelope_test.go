@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// useTestKey installs a keyring holding a fresh key and returns the key.
+func useTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := keys
+	keys = newKeyring(key)
+	t.Cleanup(func() { keys = prev })
+	return key
+}
+
+// sealStream encrypts plaintext with encryptStream, writing it in uneven
+// pieces so chunk boundaries fall inside writes.
+func sealStream(t *testing.T, pub *ecdsa.PublicKey, plaintext []byte, chunkSize int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := encryptStream(&buf, pub, "application/json", chunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rest, n := plaintext, 1; len(rest) > 0; n = n*2 + 1 {
+		n = min(n, len(rest))
+		if _, err := w.Write(rest[:n]); err != nil {
+			t.Fatal(err)
+		}
+		rest = rest[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// splitStream cuts a version 2 envelope into its preamble (magic, header
+// length and header) and its chunks, each with its length prefix.
+func splitStream(t *testing.T, envelope []byte) (pre []byte, chunks [][]byte) {
+	t.Helper()
+	n := len(streamMagic) + 4
+	n += int(binary.BigEndian.Uint32(envelope[len(streamMagic):n]))
+	pre, rest := envelope[:n], envelope[n:]
+	for len(rest) > 0 {
+		size := streamChunkHeader + int(binary.BigEndian.Uint32(rest)&^streamFinalBit)
+		chunks = append(chunks, rest[:size])
+		rest = rest[size:]
+	}
+	return pre, chunks
+}
+
+func joinStream(pre []byte, chunks ...[]byte) []byte {
+	return bytes.Join(append([][]byte{pre}, chunks...), nil)
+}
+
+// openStream decrypts envelope in full.
+func openStream(envelope []byte) (envelopeMeta, []byte, error) {
+	meta, r, err := decryptStream(bytes.NewReader(envelope))
+	if err != nil {
+		return meta, nil, err
+	}
+	plaintext, err := io.ReadAll(r)
+	return meta, plaintext, err
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	key := useTestKey(t)
+	const chunkSize = 64
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + chunkSize/2} {
+		plaintext := make([]byte, size)
+		for i := range plaintext {
+			plaintext[i] = byte(i)
+		}
+		envelope := sealStream(t, &key.PublicKey, plaintext, chunkSize)
+		if _, chunks := splitStream(t, envelope); len(chunks) != max(1, (size+chunkSize-1)/chunkSize) {
+			t.Errorf("%d bytes: sealed into %d chunks", size, len(chunks))
+		}
+
+		meta, got, err := openStream(envelope)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("%d bytes: decrypted %d different bytes", size, len(got))
+		}
+		want := envelopeMeta{Version: envelopeStream, KeyID: keyID(&key.PublicKey), ContentType: "application/json"}
+		if meta != want {
+			t.Errorf("%d bytes: meta %+v, want %+v", size, meta, want)
+		}
+	}
+}
+
+func TestStreamTruncated(t *testing.T) {
+	key := useTestKey(t)
+	envelope := sealStream(t, &key.PublicKey, bytes.Repeat([]byte("data"), 100), 64)
+	pre, chunks := splitStream(t, envelope)
+
+	for name, cut := range map[string][]byte{
+		"last chunk dropped": joinStream(pre, chunks[:len(chunks)-1]...),
+		"inside a chunk":     envelope[:len(envelope)-10],
+		"inside a length":    joinStream(pre, chunks[0], chunks[1][:2]),
+		"after the header":   pre,
+	} {
+		_, _, err := openStream(cut)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: got %v, want %v", name, err, io.ErrUnexpectedEOF)
+			continue
+		}
+		if !isTransient(classifyErr("fetch dataset", err)) {
+			t.Errorf("%s: truncation is not retried", name)
+		}
+	}
+}
+
+func TestStreamChunksTampered(t *testing.T) {
+	key := useTestKey(t)
+	pre, chunks := splitStream(t, sealStream(t, &key.PublicKey, bytes.Repeat([]byte("data"), 100), 64))
+	if len(chunks) < 3 {
+		t.Fatalf("only %d chunks", len(chunks))
+	}
+	markedFinal := bytes.Clone(chunks[0])
+	markedFinal[0] |= streamFinalBit >> 24
+	flipped := bytes.Clone(chunks[1])
+	flipped[len(flipped)-1] ^= 1
+
+	for name, envelope := range map[string][]byte{
+		"reordered":        joinStream(pre, append([][]byte{chunks[1], chunks[0]}, chunks[2:]...)...),
+		"first dropped":    joinStream(pre, chunks[1:]...),
+		"middle dropped":   joinStream(pre, append([][]byte{chunks[0]}, chunks[2:]...)...),
+		"repeated":         joinStream(pre, append([][]byte{chunks[0]}, chunks...)...),
+		"marked final":     joinStream(pre, markedFinal),
+		"ciphertext flips": joinStream(pre, append([][]byte{chunks[0], flipped}, chunks[2:]...)...),
+	} {
+		_, _, err := openStream(envelope)
+		if err == nil || isTransient(err) || !strings.Contains(err.Error(), "failed authentication") {
+			t.Errorf("%s: got %v, want a permanent authentication failure", name, err)
+		}
+	}
+}
+
+func TestStreamTrailingData(t *testing.T) {
+	key := useTestKey(t)
+	envelope := sealStream(t, &key.PublicKey, []byte(`[{"heartRate":72}]`), 64)
+	_, chunks := splitStream(t, envelope)
+
+	for name, extra := range map[string][]byte{
+		"garbage":    []byte("x"),
+		"last chunk": chunks[len(chunks)-1],
+	} {
+		_, _, err := openStream(append(bytes.Clone(envelope), extra...))
+		if err == nil || isTransient(err) || !strings.Contains(err.Error(), "data after the last chunk") {
+			t.Errorf("%s: got %v, want a permanent error", name, err)
+		}
+	}
+}
+
+func TestStreamHeaderTampered(t *testing.T) {
+	key := useTestKey(t)
+	envelope := sealStream(t, &key.PublicKey, []byte(`[{"heartRate":72}]`), 64)
+	pre, chunks := splitStream(t, envelope)
+	var h streamHeader
+	if err := json.Unmarshal(pre[len(streamMagic)+4:], &h); err != nil {
+		t.Fatal(err)
+	}
+
+	// rewrite re-encodes the header after edit, so only the authentication
+	// of the chunks can notice.
+	rewrite := func(edit func(*streamHeader)) []byte {
+		h := h
+		edit(&h)
+		aad, err := json.Marshal(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pre := binary.BigEndian.AppendUint32([]byte(streamMagic), uint32(len(aad)))
+		return joinStream(append(pre, aad...), chunks...)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		envelope []byte
+		want     string
+	}{
+		{"content type", rewrite(func(h *streamHeader) { h.ContentType = "text/csv" }), "failed authentication"},
+		{"chunk size", rewrite(func(h *streamHeader) { h.ChunkSize = 128 }), "failed authentication"},
+		{"nonce prefix", rewrite(func(h *streamHeader) { h.NoncePrefix = make([]byte, streamPrefixSize) }), "failed authentication"},
+		{"unknown key", rewrite(func(h *streamHeader) { h.KeyID = keyID(&other.PublicKey) }), "unknown key"},
+		{"version", rewrite(func(h *streamHeader) { h.Version = 3 }), "unsupported envelope version"},
+		{"oversized", binary.BigEndian.AppendUint32([]byte(streamMagic), maxStreamHeader+1), "exceeds"},
+		{"not JSON", joinStream(binary.BigEndian.AppendUint32([]byte(streamMagic), 2), []byte("{x")), "invalid envelope header"},
+	} {
+		_, _, err := openStream(tc.envelope)
+		if err == nil || isTransient(err) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want a permanent error containing %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// pipelineError is a failed pipeline step, tagged with whether trying again
+// later could succeed.
+type pipelineError struct {
+	Op        string
+	Err       error
+	Transient bool
+}
+
+func (e *pipelineError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Op, e.Err)
+}
+
+func (e *pipelineError) Unwrap() error {
+	return e.Err
+}
+
+// transientErr marks err as worth retrying, e.g. a network failure.
+func transientErr(op string, err error) error {
+	return &pipelineError{Op: op, Err: err, Transient: true}
+}
+
+// permanentErr marks err as final, e.g. data that can never be decrypted.
+func permanentErr(op string, err error) error {
+	return &pipelineError{Op: op, Err: err, Transient: false}
+}
+
+// classifyErr wraps err for op, deciding from the error itself whether it is
+// transient. Errors that are already classified keep their classification.
+func classifyErr(op string, err error) error {
+	var pe *pipelineError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return &pipelineError{Op: op, Err: err, Transient: looksTransient(err)}
+}
+
+// isTransient reports whether err may succeed when retried.
+func isTransient(err error) bool {
+	var pe *pipelineError
+	if errors.As(err, &pe) {
+		return pe.Transient
+	}
+	return looksTransient(err)
+}
+
+// httpStatusError is a non-2xx response from an HTTP service such as the IPFS
+// gateway.
+type httpStatusError struct {
+	URL    string
+	Status int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", e.URL, e.Status, http.StatusText(e.Status))
+}
+
+// Transient treats gateway overload and server errors as retryable.
+func (e *httpStatusError) Transient() bool {
+	return e.Status >= 500 || e.Status == http.StatusTooManyRequests || e.Status == http.StatusRequestTimeout
+}
+
+// Revert reasons and node errors that will not change on retry.
+var permanentMessages = []string{
+	"order already done",
+	"order expired",
+	"dataset inactive",
+	"unauthorised",
+	"insufficient funds",
+}
+
+// Node and transport errors that usually clear up by themselves. go-ethereum
+// flattens most of these into strings, so they are matched by message.
+var transientMessages = []string{
+	"nonce too low",
+	"replacement transaction underpriced",
+	"already known",
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"i/o timeout",
+	"no such host",
+	"tls handshake timeout",
+	"too many requests",
+	"bad gateway",
+	"service unavailable",
+	"gateway timeout",
+	"context deadline exceeded",
+	"eof",
+}
+
+func looksTransient(err error) bool {
+	var se *httpStatusError
+	if errors.As(err, &se) {
+		return se.Transient()
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, m := range permanentMessages {
+		if strings.Contains(msg, m) {
+			return false
+		}
+	}
+	for _, m := range transientMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// orderTTL mirrors the `order.timestamp + 1 days` check in
+// HealthTrust.completeOrder.
+const orderTTL = 24 * time.Hour
+
+// expiryMargin is how long before the deadline we stop starting work on an
+// order: compute, pinning and settlement must all fit in it.
+var expiryMargin = time.Duration(envInt("EXPIRY_MARGIN_SECONDS", 300)) * time.Second
+
+// orderDeadline is the last moment completeOrder can succeed for an order
+// created at timestamp (unix seconds, a uint40 on chain).
+func orderDeadline(timestamp *big.Int) time.Time {
+	return time.Unix(timestamp.Int64(), 0).UTC().Add(orderTTL)
+}
+
+// settleable reports whether there is still time to settle an order due at
+// deadline.
+func settleable(deadline time.Time) bool {
+	return time.Until(deadline) > expiryMargin
+}
+
+// blockDeadline returns the deadline of an order created in the given block.
+// The order's timestamp is the block's timestamp, so this is exact without a
+// getStake call.
+func blockDeadline(ctx context.Context, blockNumber uint64) (time.Time, error) {
+	cli, err := ethclient.Dial(RPC_URL)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer cli.Close()
+
+	header, err := cli.HeaderByNumber(ctx, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get block %d: %v", blockNumber, err)
+	}
+	return orderDeadline(new(big.Int).SetUint64(header.Time)), nil
+}
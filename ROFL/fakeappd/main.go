@@ -0,0 +1,50 @@
+// Command fakeappd stands in for rofl-appd when running the worker outside
+// ROFL. It serves sign-submit and keys/generate on a unix socket, signing
+// with PRIVATE_KEY instead of the app key and deriving keys from their id
+// alone, so TX_BACKEND=appd and KEY_SOURCE=appd can be exercised against
+// any RPC endpoint:
+//
+//	PRIVATE_KEY=... go run ./fakeappd -socket /tmp/rofl-appd.sock
+//	TX_BACKEND=appd KEY_SOURCE=appd APPD_SOCKET=/tmp/rofl-appd.sock go run .
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/LeonardoRyuta/HealthTrust/internal/fakeappd"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+func main() {
+	socket := flag.String("socket", "/tmp/rofl-appd.sock", "unix socket to listen on")
+	rpcURL := flag.String("rpc", "https://testnet.sapphire.oasis.io", "JSON-RPC endpoint to submit to")
+	flag.Parse()
+
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(os.Getenv("PRIVATE_KEY"), "0x"))
+	if err != nil {
+		log.Fatalf("invalid PRIVATE_KEY: %v", err)
+	}
+	cli, err := ethclient.Dial(*rpcURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	chain, err := fakeappd.NewRPCChain(context.Background(), cli, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	os.Remove(*socket)
+	ln, err := net.Listen("unix", *socket)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("fakeappd listening on %s, signing as %s", *socket, crypto.PubkeyToAddress(key.PublicKey).Hex())
+	log.Fatal(http.Serve(ln, fakeappd.NewHandler(chain)))
+}
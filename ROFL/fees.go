@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// MAX_FEE_GWEI caps the gas price (legacy) or fee cap (EIP-1559) of
+	// any transaction, including replacements. 0 means no cap.
+	maxFeePerGas = gwei(envInt("MAX_FEE_GWEI", 0))
+	// MAX_TIP_GWEI caps the EIP-1559 priority fee. 0 means no cap.
+	maxTipPerGas = gwei(envInt("MAX_TIP_GWEI", 0))
+	// A transaction not mined within stuckTxTimeout is replaced with
+	// bumped fees, at most maxFeeBumps times.
+	stuckTxTimeout = time.Duration(envInt("STUCK_TX_SECONDS", 120)) * time.Second
+	maxFeeBumps    = envInt("MAX_FEE_BUMPS", 5)
+)
+
+func gwei(n int) *big.Int {
+	if n <= 0 {
+		return nil
+	}
+	return new(big.Int).Mul(big.NewInt(int64(n)), big.NewInt(params.GWei))
+}
+
+// txFees is the fee part of a transaction: GasPrice for legacy transactions,
+// or GasTipCap and GasFeeCap for EIP-1559 ones.
+type txFees struct {
+	GasPrice  *big.Int
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+func (f txFees) dynamic() bool {
+	return f.GasFeeCap != nil
+}
+
+func (f txFees) String() string {
+	if f.dynamic() {
+		return fmt.Sprintf("fee cap %s wei, tip %s wei", f.GasFeeCap, f.GasTipCap)
+	}
+	return fmt.Sprintf("gas price %s wei", f.GasPrice)
+}
+
+func (f txFees) apply(opts *bind.TransactOpts) {
+	opts.GasPrice, opts.GasTipCap, opts.GasFeeCap = f.GasPrice, f.GasTipCap, f.GasFeeCap
+}
+
+// suggestFees uses EIP-1559 fees when the chain reports a base fee, and a
+// legacy gas price otherwise. The fee cap leaves room for the base fee to
+// double before the transaction stops being includable.
+func suggestFees(ctx context.Context, cli *ethclient.Client) (txFees, error) {
+	head, err := cli.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return txFees{}, fmt.Errorf("failed to get latest header: %v", err)
+	}
+
+	if head.BaseFee == nil {
+		gasPrice, err := cli.SuggestGasPrice(ctx)
+		if err != nil {
+			return txFees{}, fmt.Errorf("failed to suggest gas price: %v", err)
+		}
+		return txFees{GasPrice: capFee(gasPrice, maxFeePerGas)}, nil
+	}
+
+	tip, err := cli.SuggestGasTipCap(ctx)
+	if err != nil {
+		return txFees{}, fmt.Errorf("failed to suggest gas tip cap: %v", err)
+	}
+	tip = capFee(tip, maxTipPerGas)
+	feeCap := new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tip)
+	feeCap = capFee(feeCap, maxFeePerGas)
+	return txFees{GasTipCap: capFee(tip, feeCap), GasFeeCap: feeCap}, nil
+}
+
+// bump raises every fee by 12.5%, above the 10% nodes require to accept a
+// replacement, without exceeding the configured caps. ok is false when the
+// caps leave no room to bump.
+func (f txFees) bump() (bumped txFees, ok bool) {
+	up := func(v, limit *big.Int) *big.Int {
+		if v == nil {
+			return nil
+		}
+		n := new(big.Int).Add(v, new(big.Int).Div(v, big.NewInt(8)))
+		n.Add(n, big.NewInt(1))
+		return capFee(n, limit)
+	}
+
+	if !f.dynamic() {
+		bumped = txFees{GasPrice: up(f.GasPrice, maxFeePerGas)}
+		return bumped, bumped.GasPrice.Cmp(f.GasPrice) > 0
+	}
+	bumped.GasFeeCap = up(f.GasFeeCap, maxFeePerGas)
+	bumped.GasTipCap = capFee(up(f.GasTipCap, maxTipPerGas), bumped.GasFeeCap)
+	// Nodes require both fields to go up for a replacement.
+	return bumped, bumped.GasFeeCap.Cmp(f.GasFeeCap) > 0 && bumped.GasTipCap.Cmp(f.GasTipCap) > 0
+}
+
+func capFee(v, limit *big.Int) *big.Int {
+	if limit != nil && v.Cmp(limit) > 0 {
+		return new(big.Int).Set(limit)
+	}
+	return v
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// FHIR R4 Bundles of vital sign Observations, as hospitals and health record
+// exports produce them, are imported under this content type. An optional
+// fhirVersion parameter must name an R4 release.
+const contentFHIR = "application/fhir+json"
+
+const (
+	systemLOINC = "http://loinc.org"
+	systemUCUM  = "http://unitsofmeasure.org"
+)
+
+// loincMetrics maps the LOINC codes of the Observations we import to the
+// registry. Anything else is rejected as an unsupported code.
+var loincMetrics = map[string]MetricType{
+	"59408-5": MetricBloodOxygen,          // Oxygen saturation in Arterial blood by Pulse oximetry
+	"2708-6":  MetricBloodOxygen,          // Oxygen saturation in Arterial blood
+	"8867-4":  MetricHeartRate,            // Heart rate
+	"9279-1":  MetricRespiratoryRate,      // Respiratory rate
+	"55423-8": MetricSteps,                // Number of steps in unspecified time Pedometer
+	"41950-7": MetricSteps,                // Number of steps in 24 hour Measured
+	"85354-9": MetricBloodPressure,        // Blood pressure panel with all children optional
+	"35094-2": MetricBloodPressure,        // Blood pressure panel
+	"2339-0":  MetricGlucose,              // Glucose [Mass/volume] in Blood
+	"2345-7":  MetricGlucose,              // Glucose [Mass/volume] in Serum or Plasma
+	"15074-8": MetricGlucose,              // Glucose [Moles/volume] in Blood
+	"8310-5":  MetricBodyTemperature,      // Body temperature
+	"80404-7": MetricHeartRateVariability, // R-R interval.standard deviation (Heart rate variability)
+	"29463-7": MetricWeight,               // Body weight
+	"3141-9":  MetricWeight,               // Body weight Measured
+}
+
+// loincComponents maps the LOINC codes of panel components to component
+// names of the panel's metric.
+var loincComponents = map[string]string{
+	"8480-6": "systolic",  // Systolic blood pressure
+	"8462-4": "diastolic", // Diastolic blood pressure
+}
+
+// unitConversion turns a quantity into the registry unit of its metric.
+type unitConversion struct {
+	scale, offset float64
+}
+
+func (u unitConversion) apply(v float64) float64 {
+	return v*u.scale + u.offset
+}
+
+var sameUnit = unitConversion{scale: 1}
+
+// ucumUnits lists the UCUM units accepted for each metric and how to convert
+// them to the unit in metricRegistry.
+var ucumUnits = map[MetricType]map[string]unitConversion{
+	MetricBloodOxygen:     {"%": sameUnit},
+	MetricHeartRate:       {"/min": sameUnit, "{beats}/min": sameUnit, "{beat}/min": sameUnit},
+	MetricRespiratoryRate: {"/min": sameUnit, "{breaths}/min": sameUnit, "{breath}/min": sameUnit},
+	MetricSteps:           {"": sameUnit, "1": sameUnit, "{steps}": sameUnit, "{count}": sameUnit},
+	MetricBloodPressure:   {"mm[Hg]": sameUnit},
+	MetricGlucose: {
+		"mg/dL":  sameUnit,
+		"mmol/L": {scale: 18.016}, // molar mass of glucose, 180.16 g/mol
+	},
+	MetricBodyTemperature: {
+		"Cel":    sameUnit,
+		"[degF]": {scale: 5.0 / 9, offset: -32.0 * 5 / 9},
+		"K":      {scale: 1, offset: -273.15},
+	},
+	MetricHeartRateVariability: {"ms": sameUnit, "s": {scale: 1000}},
+	MetricWeight: {
+		"kg":      sameUnit,
+		"g":       {scale: 0.001},
+		"[lb_av]": {scale: 0.45359237},
+	},
+}
+
+// Observation statuses whose values are imported; the rest are unfinished,
+// cancelled or entered in error.
+var fhirStatuses = map[string]bool{"final": true, "amended": true, "corrected": true}
+
+// Reasons an Observation is not imported, counted in Data.Rejected. An
+// unsupported code, status or unit is named in its reason.
+const (
+	rejectMalformed = "malformed Observation"
+	rejectNoTime    = "no effective time"
+	rejectNoValue   = "no value"
+)
+
+type fhirObservation struct {
+	ResourceType      string          `json:"resourceType"`
+	Status            string          `json:"status"`
+	Code              fhirConcept     `json:"code"`
+	EffectiveDateTime string          `json:"effectiveDateTime"`
+	EffectiveInstant  string          `json:"effectiveInstant"`
+	EffectivePeriod   *fhirPeriod     `json:"effectivePeriod"`
+	ValueQuantity     *fhirQuantity   `json:"valueQuantity"`
+	Component         []fhirComponent `json:"component"`
+}
+
+type fhirConcept struct {
+	Coding []fhirCoding `json:"coding"`
+}
+
+type fhirCoding struct {
+	System string `json:"system"`
+	Code   string `json:"code"`
+}
+
+type fhirPeriod struct {
+	Start string `json:"start"`
+}
+
+type fhirQuantity struct {
+	Value  *float64 `json:"value"`
+	Unit   string   `json:"unit"`
+	System string   `json:"system"`
+	Code   string   `json:"code"`
+}
+
+type fhirComponent struct {
+	Code          fhirConcept   `json:"code"`
+	ValueQuantity *fhirQuantity `json:"valueQuantity"`
+}
+
+// fhirDecoder reads a Bundle and imports each of its Observations as one
+// measurement. Observations with the same effective time become one entry,
+// as a device records them together. Resources other than Observations are
+// skipped unread; Observations that cannot be imported are counted by
+// reason in Data.Rejected. Bundle entries are decoded one at a time, but
+// grouping by time needs the whole Bundle, so the entries are only passed
+// to yield once it has been read.
+type fhirDecoder struct{}
+
+func (fhirDecoder) Decode(r io.Reader, yield func(DataEntry)) (Data, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return Data{}, err
+	}
+
+	imp := newFHIRImport()
+	resourceType := ""
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return Data{}, syntaxOrReadErr(err)
+		}
+		switch tok {
+		case "resourceType":
+			if err := dec.Decode(&resourceType); err != nil {
+				return Data{}, syntaxOrReadErr(err)
+			}
+		case "entry":
+			if err := imp.decodeEntries(dec); err != nil {
+				return Data{}, err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return Data{}, syntaxOrReadErr(err)
+			}
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return Data{}, err
+	}
+	if resourceType != "Bundle" {
+		return Data{}, permanentErr("parse dataset", fmt.Errorf("want a FHIR Bundle, got resourceType %q", resourceType))
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return Data{}, syntaxOrReadErr(err)
+		}
+		return Data{}, permanentErr("parse dataset", errors.New("data after the Bundle"))
+	}
+	for _, e := range imp.entries {
+		yield(e)
+	}
+	return imp.data(), nil
+}
+
+// fhirImport collects the entries of a Bundle.
+type fhirImport struct {
+	entries  []DataEntry
+	byTime   map[int64]int // timestamp -> index in entries
+	rejected map[string]int
+}
+
+func newFHIRImport() *fhirImport {
+	return &fhirImport{byTime: make(map[int64]int), rejected: make(map[string]int)}
+}
+
+func (imp *fhirImport) decodeEntries(dec *json.Decoder) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		var e struct {
+			Resource json.RawMessage `json:"resource"`
+		}
+		if err := dec.Decode(&e); err != nil {
+			return syntaxOrReadErr(err)
+		}
+		imp.add(e.Resource)
+	}
+	return expectDelim(dec, ']')
+}
+
+// add imports one Bundle entry's resource.
+func (imp *fhirImport) add(resource json.RawMessage) {
+	var kind struct {
+		ResourceType string `json:"resourceType"`
+	}
+	if len(resource) == 0 || json.Unmarshal(resource, &kind) != nil || kind.ResourceType != "Observation" {
+		return
+	}
+	var obs fhirObservation
+	if err := json.Unmarshal(resource, &obs); err != nil {
+		imp.rejected[rejectMalformed]++
+		return
+	}
+
+	ts, t, v, reason := obs.measurement()
+	if reason != "" {
+		imp.rejected[reason]++
+		return
+	}
+	i, ok := imp.byTime[ts]
+	if !ok {
+		imp.byTime[ts] = len(imp.entries)
+		imp.entries = append(imp.entries, DataEntry{Timestamp: ts, Values: map[MetricType]metricValue{t: v}})
+		return
+	}
+	if _, ok := imp.entries[i].Values[t]; ok {
+		imp.rejected[rejectDuplicate]++
+		return
+	}
+	imp.entries[i].Values[t] = v
+}
+
+func (imp *fhirImport) data() Data {
+	d := Data{SchemaVersion: importedSchema}
+	if len(imp.rejected) > 0 {
+		d.Rejected = imp.rejected
+	}
+	return d
+}
+
+// measurement maps the Observation onto the registry, or returns the reason
+// it cannot be.
+func (o fhirObservation) measurement() (ts int64, t MetricType, v metricValue, reason string) {
+	if !fhirStatuses[o.Status] {
+		return 0, 0, nil, fmt.Sprintf("status %q", o.Status)
+	}
+	code, t, ok := o.Code.metric()
+	if !ok {
+		return 0, 0, nil, unsupportedCode(code)
+	}
+	def := metricRegistry[t]
+
+	ts, ok = o.effective()
+	if !ok {
+		return 0, 0, nil, rejectNoTime
+	}
+
+	if def.Kind != kindPair {
+		x, reason := convertQuantity(def, o.ValueQuantity)
+		if reason != "" {
+			return 0, 0, nil, reason
+		}
+		return ts, t, metricValue{x}, ""
+	}
+
+	v = make(metricValue, len(def.Components))
+	found := make([]bool, len(def.Components))
+	for _, c := range o.Component {
+		i := c.Code.component(def)
+		if i < 0 || found[i] {
+			continue
+		}
+		x, reason := convertQuantity(def, c.ValueQuantity)
+		if reason != "" {
+			return 0, 0, nil, reason
+		}
+		v[i], found[i] = x, true
+	}
+	for i, ok := range found {
+		if !ok {
+			return 0, 0, nil, fmt.Sprintf("%s without %s", def.Name, def.Components[i])
+		}
+	}
+	return ts, t, v, ""
+}
+
+// metric finds the first coding with a supported LOINC code. code names the
+// first coding for the report when there is none.
+func (c fhirConcept) metric() (code string, t MetricType, ok bool) {
+	for _, coding := range c.Coding {
+		if coding.System != systemLOINC {
+			continue
+		}
+		if t, ok := loincMetrics[coding.Code]; ok {
+			return coding.Code, t, true
+		}
+	}
+	if len(c.Coding) == 0 {
+		return "", 0, false
+	}
+	first := c.Coding[0]
+	if first.System == systemLOINC {
+		return "LOINC " + first.Code, 0, false
+	}
+	return first.System + "|" + first.Code, 0, false
+}
+
+// component returns the index in def.Components the concept codes for, or -1.
+func (c fhirConcept) component(def metricDef) int {
+	for _, coding := range c.Coding {
+		if coding.System != systemLOINC {
+			continue
+		}
+		for i, name := range def.Components {
+			if loincComponents[coding.Code] == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func unsupportedCode(code string) string {
+	if code == "" {
+		return "no code"
+	}
+	return "unsupported code " + code
+}
+
+// effective returns the Observation's effective time in Unix seconds. A date
+// without a time is taken as midnight UTC.
+func (o fhirObservation) effective() (int64, bool) {
+	s := o.EffectiveDateTime
+	if s == "" {
+		s = o.EffectiveInstant
+	}
+	if s == "" && o.EffectivePeriod != nil {
+		s = o.EffectivePeriod.Start
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Unix(), true
+		}
+	}
+	return 0, false
+}
+
+// convertQuantity converts q to the registry unit of def. The UCUM code is
+// used when present; otherwise the unit text must be a UCUM code.
+func convertQuantity(def metricDef, q *fhirQuantity) (float64, string) {
+	if q == nil || q.Value == nil {
+		return 0, rejectNoValue
+	}
+	unit := q.Unit
+	if q.Code != "" && (q.System == systemUCUM || q.System == "") {
+		unit = q.Code
+	}
+	conv, ok := ucumUnits[def.Type][unit]
+	if !ok {
+		return 0, fmt.Sprintf("unsupported unit %q for %s", unit, def.Name)
+	}
+	x := conv.apply(*q.Value)
+	if def.Kind == kindInteger && x != math.Trunc(x) {
+		return 0, fmt.Sprintf("%s not a whole number", def.Name)
+	}
+	return x, ""
+}
+
+// fhirVersion checks the fhirVersion media type parameter.
+func fhirVersion(v string) error {
+	if v != "" && v != "4.0" && !strings.HasPrefix(v, "4.0.") {
+		return fmt.Errorf("unsupported fhirVersion %q, want 4.0 (R4)", v)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	fhirT1 = "2024-05-01T07:30:00Z" // 1714548600
+	fhirT2 = "2024-05-01T07:31:00+00:00"
+)
+
+// observation is a final vital sign Observation with a single quantity.
+func observation(loinc string, value float64, unit, effective string) string {
+	return fmt.Sprintf(`{"resourceType":"Observation","status":"final",
+		"code":{"coding":[{"system":"http://loinc.org","code":%q}]},
+		"effectiveDateTime":%q,
+		"valueQuantity":{"value":%v,"system":"http://unitsofmeasure.org","code":%q}}`,
+		loinc, effective, value, unit)
+}
+
+// bloodPressure is a blood pressure panel Observation with the given
+// components, each a LOINC code and a value in mm[Hg].
+func bloodPressure(effective string, components ...any) string {
+	var parts []string
+	for i := 0; i < len(components); i += 2 {
+		parts = append(parts, fmt.Sprintf(`{"code":{"coding":[{"system":"http://loinc.org","code":%q}]},
+			"valueQuantity":{"value":%v,"unit":"mmHg","system":"http://unitsofmeasure.org","code":"mm[Hg]"}}`,
+			components[i], components[i+1]))
+	}
+	return fmt.Sprintf(`{"resourceType":"Observation","status":"final",
+		"code":{"coding":[{"system":"http://loinc.org","code":"85354-9"}]},
+		"effectiveDateTime":%q,"component":[%s]}`, effective, strings.Join(parts, ","))
+}
+
+func bundle(resources ...string) string {
+	entries := make([]string, len(resources))
+	for i, r := range resources {
+		entries[i] = `{"fullUrl":"urn:uuid:` + fmt.Sprint(i) + `","resource":` + r + `}`
+	}
+	return `{"resourceType":"Bundle","type":"collection","entry":[` + strings.Join(entries, ",") + `]}`
+}
+
+// importFHIR decodes payload as a FHIR Bundle and collects its entries.
+func importFHIR(t *testing.T, payload string) (Data, []DataEntry) {
+	t.Helper()
+	var entries []DataEntry
+	data, err := decodePayload(contentFHIR, strings.NewReader(payload), func(e DataEntry) {
+		entries = append(entries, e)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, entries
+}
+
+func TestFHIRUnitConversion(t *testing.T) {
+	for _, tc := range []struct {
+		loinc string
+		value float64
+		unit  string
+		want  float64
+	}{
+		{"2339-0", 99, "mg/dL", 99},
+		{"15074-8", 5.5, "mmol/L", 99.088},
+		{"8310-5", 37.2, "Cel", 37.2},
+		{"8310-5", 98.6, "[degF]", 37},
+		{"8310-5", 310.15, "K", 37},
+		{"29463-7", 72.5, "kg", 72.5},
+		{"29463-7", 72500, "g", 72.5},
+		{"29463-7", 150, "[lb_av]", 68.0388555},
+		{"80404-7", 45, "ms", 45},
+		{"80404-7", 0.045, "s", 45},
+		{"8867-4", 61, "/min", 61},
+		{"8867-4", 61, "{beats}/min", 61},
+		{"9279-1", 14, "{breaths}/min", 14},
+		{"59408-5", 97.5, "%", 97.5},
+		{"55423-8", 4200, "{steps}", 4200},
+	} {
+		data, entries := importFHIR(t, bundle(observation(tc.loinc, tc.value, tc.unit, fhirT1)))
+		if len(data.Rejected) != 0 || len(entries) != 1 {
+			t.Errorf("%s %v %s: %d entries, rejected %v", tc.loinc, tc.value, tc.unit, len(entries), data.Rejected)
+			continue
+		}
+		mt := loincMetrics[tc.loinc]
+		got := entries[0].Values[mt]
+		if len(got) != 1 || math.Abs(got[0]-tc.want) > 1e-9*max(1, tc.want) {
+			t.Errorf("%s %v %s: got %v %s, want %v", tc.loinc, tc.value, tc.unit, got, metricRegistry[mt].Unit, tc.want)
+		}
+	}
+}
+
+// TestFHIRUnitText checks that the unit text is read as UCUM when the
+// quantity has no UCUM code.
+func TestFHIRUnitText(t *testing.T) {
+	obs := `{"resourceType":"Observation","status":"amended",
+		"code":{"coding":[{"system":"http://snomed.info/sct","code":"27113001"},{"system":"http://loinc.org","code":"29463-7"}]},
+		"effectiveInstant":"2024-05-01T07:30:00.123Z",
+		"valueQuantity":{"value":165,"unit":"[lb_av]","system":"http://example.org/units","code":"LB"}}`
+	_, entries := importFHIR(t, bundle(obs))
+	if len(entries) != 1 || math.Abs(entries[0].Values[MetricWeight][0]-74.8427) > 1e-4 {
+		t.Fatalf("entries %v", entries)
+	}
+	if entries[0].Timestamp != 1714548600 {
+		t.Errorf("timestamp %d, want 1714548600", entries[0].Timestamp)
+	}
+}
+
+func TestFHIRBundleImport(t *testing.T) {
+	payload := bundle(
+		`{"resourceType":"Patient","id":"p1","birthDate":"1970-01-01"}`,
+		observation("8867-4", 61, "/min", fhirT1),
+		observation("59408-5", 97, "%", fhirT1),
+		bloodPressure(fhirT2, "8480-6", 118, "8462-4", 76),
+		`{"resourceType":"Observation","status":"final","code":{"coding":[{"system":"http://loinc.org","code":"8867-4"}]},
+			"effectivePeriod":{"start":"2024-05-01T07:31:00Z","end":"2024-05-01T07:32:00Z"},
+			"valueQuantity":{"value":64,"unit":"beats/minute","system":"http://unitsofmeasure.org","code":"/min"}}`,
+		observation("8310-5", 36.8, "Cel", "2024-05-02"),
+
+		// rejected
+		observation("8867-4", 62, "/min", fhirT1),
+		strings.Replace(observation("8867-4", 61, "/min", fhirT1), `"final"`, `"preliminary"`, 1),
+		strings.Replace(observation("8867-4", 61, "/min", fhirT1), `"final"`, `"entered-in-error"`, 1),
+		observation("1234-5", 1, "1", fhirT1),
+		strings.Replace(observation("8867-4", 61, "/min", fhirT1), "http://loinc.org", "http://snomed.info/sct", 1),
+		`{"resourceType":"Observation","status":"final","code":{"text":"heart rate"},"effectiveDateTime":"2024-05-01T07:30:00Z"}`,
+		observation("8310-5", 37, "mg", fhirT1),
+		observation("55423-8", 10.5, "1", fhirT1),
+		observation("8867-4", 61, "/min", "yesterday"),
+		`{"resourceType":"Observation","status":"final","code":{"coding":[{"system":"http://loinc.org","code":"8867-4"}]},
+			"effectiveDateTime":"2024-05-01T07:32:00Z"}`,
+		bloodPressure(fhirT1, "8480-6", 120),
+		`{"resourceType":"Observation","status":"final","code":{"coding":[{"system":"http://loinc.org","code":"8867-4"}]},
+			"effectiveDateTime":"2024-05-01T07:32:00Z","valueQuantity":{"value":"61","code":"/min"}}`,
+	)
+	data, entries := importFHIR(t, payload)
+
+	want := []DataEntry{
+		{Timestamp: 1714548600, Values: map[MetricType]metricValue{MetricHeartRate: {61}, MetricBloodOxygen: {97}}},
+		{Timestamp: 1714548660, Values: map[MetricType]metricValue{MetricBloodPressure: {118, 76}, MetricHeartRate: {64}}},
+		{Timestamp: 1714608000, Values: map[MetricType]metricValue{MetricBodyTemperature: {36.8}}},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries\n%v\nwant\n%v", entries, want)
+	}
+
+	wantRejected := map[string]int{
+		rejectDuplicate:                                  1,
+		`status "preliminary"`:                           1,
+		`status "entered-in-error"`:                      1,
+		"unsupported code LOINC 1234-5":                  1,
+		"unsupported code http://snomed.info/sct|8867-4": 1,
+		"no code": 1,
+		`unsupported unit "mg" for bodyTemperature`: 1,
+		"steps not a whole number":                  1,
+		rejectNoTime:                                1,
+		rejectNoValue:                               1,
+		"bloodPressure without diastolic":           1,
+		rejectMalformed:                             1,
+	}
+	if !reflect.DeepEqual(data.Rejected, wantRejected) {
+		t.Errorf("rejected\n%v\nwant\n%v", data.Rejected, wantRejected)
+	}
+	if data.SchemaVersion != importedSchema {
+		t.Errorf("schema version %d", data.SchemaVersion)
+	}
+
+	// the rejections reach the quality report
+	v := newEntryValidator(nil)
+	for _, e := range entries {
+		v.add(e)
+	}
+	report := v.finish(data.Rejected)
+	if report.Total != 15 || report.Accepted != 3 || report.Rejected != 12 {
+		t.Errorf("report %s", report)
+	}
+}
+
+func TestFHIRRejectsPayload(t *testing.T) {
+	obs := observation("8867-4", 61, "/min", fhirT1)
+	for _, tc := range []struct {
+		name, contentType, payload, want string
+	}{
+		{"not a Bundle", contentFHIR, obs, "want a FHIR Bundle"},
+		{"data after the Bundle", contentFHIR, bundle(obs) + `{}`, "data after the Bundle"},
+		{"entry not an array", contentFHIR, `{"resourceType":"Bundle","entry":{}}`, "expected ["},
+		{"not JSON", contentFHIR, `<Bundle/>`, "invalid character"},
+		{"FHIR R3", contentFHIR + "; fhirVersion=3.0", bundle(obs), "unsupported fhirVersion"},
+	} {
+		_, err := decodePayload(tc.contentType, strings.NewReader(tc.payload), func(DataEntry) {})
+		if err == nil || isTransient(err) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want a permanent error containing %q", tc.name, err, tc.want)
+		}
+	}
+
+	if _, err := decodePayload(contentFHIR+"; fhirVersion=4.0.1", strings.NewReader(bundle(obs)), func(DataEntry) {}); err != nil {
+		t.Errorf("FHIR 4.0.1: %v", err)
+	}
+}
+
+// TestFHIRRejectionsCarryNoValues checks that rejection reasons, which are
+// pinned in the quality report, never include a measurement.
+func TestFHIRRejectionsCarryNoValues(t *testing.T) {
+	data, _ := importFHIR(t, bundle(
+		observation("8310-5", 37.123, "mg", fhirT1),
+		observation("55423-8", 4321.5, "1", fhirT1),
+	))
+	raw, err := json.Marshal(data.Rejected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoSecrets(t, string(raw), "37.123", "4321.5")
+}
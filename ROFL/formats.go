@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Besides the JSON documents in schema.go, datasets can be uploaded in the
+// formats spreadsheets and device tools export. The envelope records which
+// one the plaintext is in; envelopes that do not say are JSON.
+const (
+	contentJSON   = "application/json"
+	contentCSV    = "text/csv"
+	contentNDJSON = "application/x-ndjson"
+)
+
+// decodePayload parses a decrypted payload with the decoder for its content
+// type. Media type parameters configure the decoder, e.g.
+//
+//	text/csv; delimiter=";"; timestamp="2006-01-02 15:04"
+//	application/x-ndjson; timestamp=rfc3339
+//
+// Each entry is passed to yield as soon as it is read.
+func decodePayload(contentType string, r io.Reader, yield func(DataEntry)) (Data, error) {
+	dec, err := payloadDecoder(contentType)
+	if err != nil {
+		return Data{}, permanentErr("parse dataset", err)
+	}
+	return dec.Decode(r, yield)
+}
+
+func payloadDecoder(contentType string) (datasetDecoder, error) {
+	if contentType == "" {
+		return jsonDecoder{}, nil
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content type %q: %v", contentType, err)
+	}
+
+	switch mediaType {
+	case contentJSON:
+		return jsonDecoder{}, nil
+	case contentCSV:
+		ts, err := newTimestampFormat(params["timestamp"])
+		if err != nil {
+			return nil, err
+		}
+		comma, err := csvDelimiter(params["delimiter"])
+		if err != nil {
+			return nil, err
+		}
+		return csvDecoder{comma: comma, timestamps: ts}, nil
+	case contentNDJSON, "application/ndjson", "application/jsonl":
+		ts, err := newTimestampFormat(params["timestamp"])
+		if err != nil {
+			return nil, err
+		}
+		return ndjsonDecoder{timestamps: ts}, nil
+	case contentFHIR:
+		// ParseMediaType lowercases parameter names
+		if err := fhirVersion(params["fhirversion"]); err != nil {
+			return nil, err
+		}
+		return fhirDecoder{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", mediaType)
+	}
+}
+
+// jsonDecoder reads the versioned JSON documents of schema.go.
+type jsonDecoder struct{}
+
+func (jsonDecoder) Decode(r io.Reader, yield func(DataEntry)) (Data, error) {
+	return decodeDataset(r, yield)
+}
+
+// importedSchema is the schema version reported for CSV and NDJSON
+// payloads, whose rows carry the same metrics as version 2 entries.
+const importedSchema = 2
+
+// timestampFormat turns imported timestamps into Unix seconds, the unit the
+// JSON schemas use. It is set by the timestamp media type parameter:
+//
+//	unix     seconds since the epoch (the default)
+//	unix-ms  milliseconds since the epoch
+//	rfc3339  e.g. 2024-05-01T07:30:00Z
+//
+// Anything else is a Go time layout such as "2006-01-02 15:04"; times
+// without a zone are UTC.
+type timestampFormat string
+
+const (
+	timestampUnix    timestampFormat = "unix"
+	timestampUnixMs  timestampFormat = "unix-ms"
+	timestampRFC3339 timestampFormat = "rfc3339"
+)
+
+func newTimestampFormat(s string) (timestampFormat, error) {
+	switch f := timestampFormat(strings.ToLower(s)); f {
+	case "":
+		return timestampUnix, nil
+	case timestampUnix, timestampUnixMs, timestampRFC3339:
+		return f, nil
+	}
+	// a layout without any element formats to itself
+	if time.Unix(0, 0).UTC().Format(s) == s {
+		return "", fmt.Errorf("invalid timestamp format %q", s)
+	}
+	return timestampFormat(s), nil
+}
+
+// parse reads a timestamp. Like every error about the content of a payload,
+// its errors say what was expected but never quote the value, since they
+// are logged and kept in jobs.json.
+func (f timestampFormat) parse(s string) (int64, error) {
+	layout := string(f)
+	switch f {
+	case timestampUnix, timestampUnixMs:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("timestamp: want %s", f)
+		}
+		if f == timestampUnixMs {
+			n /= 1000
+		}
+		return n, nil
+	case timestampRFC3339:
+		layout = time.RFC3339
+	}
+	t, err := time.ParseInLocation(layout, s, time.UTC)
+	if err != nil {
+		return 0, fmt.Errorf("timestamp: want %s", layout)
+	}
+	return t.Unix(), nil
+}
+
+// csvDelimiter reads the delimiter media type parameter: a single
+// character, or "tab". The default is a comma.
+func csvDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return ',', nil
+	case "tab":
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("invalid CSV delimiter %q", s)
+	}
+	return r, nil
+}
+
+// csvDecoder reads a CSV file with a header row. One column holds the
+// timestamp and every other column a metric, or one component of a pair:
+//
+//	timestamp,heartRate,bloodPressure.systolic,bloodPressure.diastolic,sleepStage
+//	1714548600,61,118,76,deep
+//
+// Headers are matched to metric names ignoring case, spaces, underscores,
+// hyphens and a trailing unit in brackets, so "Heart Rate (bpm)" is
+// heartRate; see csvHeaders for the names accepted. An empty cell means the
+// metric was not measured at that time. Rows are read one at a time.
+type csvDecoder struct {
+	comma      rune
+	timestamps timestampFormat
+}
+
+// csvColumn is what one column of a CSV file holds.
+type csvColumn struct {
+	timestamp bool
+	def       metricDef
+	component int // index into def.Components for pairs
+}
+
+// csvHeaders maps normalized column headers to what they hold. It has every
+// metric name, "<name>.<component>" and the bare component name for pairs,
+// and the aliases below.
+var csvHeaders = indexCSVHeaders()
+
+var csvAliases = map[string]string{
+	"spo2":         "bloodOxygenLevel",
+	"oxygen":       "bloodOxygenLevel",
+	"hr":           "heartRate",
+	"pulse":        "heartRate",
+	"rr":           "respiratoryRate",
+	"sleep":        "sleepStage",
+	"bloodglucose": "glucose",
+	"temperature":  "bodyTemperature",
+	"hrv":          "heartRateVariability",
+}
+
+func indexCSVHeaders() map[string]csvColumn {
+	headers := make(map[string]csvColumn)
+	for _, h := range []string{"timestamp", "time", "date", "datetime"} {
+		headers[h] = csvColumn{timestamp: true}
+	}
+	for _, def := range metricsByName {
+		if def.Kind != kindPair {
+			headers[csvHeader(def.Name)] = csvColumn{def: def}
+			continue
+		}
+		for i, c := range def.Components {
+			headers[csvHeader(def.Name+"."+c)] = csvColumn{def: def, component: i}
+			headers[csvHeader(c)] = csvColumn{def: def, component: i}
+		}
+	}
+	for alias, name := range csvAliases {
+		headers[alias] = csvColumn{def: metricsByName[name]}
+	}
+	return headers
+}
+
+// csvHeader normalizes a column header for lookup in csvHeaders.
+func csvHeader(h string) string {
+	h = strings.TrimSpace(h)
+	if i := strings.LastIndexAny(h, "(["); i > 0 && strings.ContainsAny(h[len(h)-1:], ")]") {
+		h = h[:i]
+	}
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '_' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(h))
+}
+
+func (c csvDecoder) Decode(r io.Reader, yield func(DataEntry)) (Data, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = c.comma
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return Data{}, permanentErr("parse dataset", errors.New("empty CSV file"))
+	}
+	if err != nil {
+		return Data{}, csvErr(err)
+	}
+	cols, err := csvColumns(header)
+	if err != nil {
+		return Data{}, permanentErr("parse dataset", err)
+	}
+
+	d := Data{SchemaVersion: importedSchema}
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Data{}, csvErr(err)
+		}
+		e, err := c.entry(cols, rec)
+		if err != nil {
+			line, _ := cr.FieldPos(0)
+			return Data{}, permanentErr("parse dataset", fmt.Errorf("line %d: %w", line, err))
+		}
+		yield(e)
+	}
+	return d, nil
+}
+
+// csvColumns resolves a header row. Every column must be known, exactly one
+// must hold the timestamp and pairs need a column for each component.
+func csvColumns(header []string) ([]csvColumn, error) {
+	cols := make([]csvColumn, len(header))
+	seen := make(map[string]string) // what a column holds -> its header
+	for i, h := range header {
+		col, ok := csvHeaders[csvHeader(h)]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", h)
+		}
+		what := "timestamp"
+		if !col.timestamp {
+			what = col.def.Name
+			if col.def.Kind == kindPair {
+				what += "." + col.def.Components[col.component]
+			}
+		}
+		if prev, ok := seen[what]; ok {
+			return nil, fmt.Errorf("columns %q and %q both hold %s", prev, h, what)
+		}
+		seen[what] = h
+		cols[i] = col
+	}
+
+	if _, ok := seen["timestamp"]; !ok {
+		return nil, errors.New("no timestamp column")
+	}
+	for _, col := range cols {
+		for _, c := range col.def.Components {
+			if _, ok := seen[col.def.Name+"."+c]; !ok {
+				return nil, fmt.Errorf("%s has no %s column", col.def.Name, c)
+			}
+		}
+	}
+	return cols, nil
+}
+
+func (c csvDecoder) entry(cols []csvColumn, rec []string) (DataEntry, error) {
+	var e DataEntry
+	e.Values = make(map[MetricType]metricValue)
+	// components of pairs whose cell was empty
+	blank := make(map[MetricType][]string)
+	for i, col := range cols {
+		cell := strings.TrimSpace(rec[i])
+		if col.timestamp {
+			ts, err := c.timestamps.parse(cell)
+			if err != nil {
+				return DataEntry{}, fmt.Errorf("column %d: %w", i+1, err)
+			}
+			e.Timestamp = ts
+			continue
+		}
+		if cell == "" {
+			if col.def.Kind == kindPair {
+				blank[col.def.Type] = append(blank[col.def.Type], col.def.Components[col.component])
+			}
+			continue
+		}
+
+		x, err := parseCell(col.def, cell)
+		if err != nil {
+			return DataEntry{}, fmt.Errorf("column %d: %w", i+1, err)
+		}
+		v, ok := e.Values[col.def.Type]
+		if !ok {
+			v = make(metricValue, max(len(col.def.Components), 1))
+			e.Values[col.def.Type] = v
+		}
+		v[col.component] = x
+	}
+	for t, missing := range blank {
+		if _, ok := e.Values[t]; ok {
+			return DataEntry{}, fmt.Errorf("%s: missing %s", metricRegistry[t].Name, strings.Join(missing, ", "))
+		}
+	}
+	return e, nil
+}
+
+// parseCell reads one CSV cell: a number for integer and decimal metrics and
+// pair components, a label for categories.
+func parseCell(def metricDef, s string) (float64, error) {
+	if def.Kind == kindCategory {
+		i := slices.Index(def.Categories, strings.ToLower(s))
+		if i < 0 {
+			return 0, fmt.Errorf("%s: not one of %s", def.Name, strings.Join(def.Categories, ", "))
+		}
+		return float64(i), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%s: want a number", def.Name)
+	}
+	if def.Kind == kindInteger && v != math.Trunc(v) {
+		return 0, fmt.Errorf("%s: want an integer", def.Name)
+	}
+	return v, nil
+}
+
+// csvErr marks malformed CSV as permanent and passes read errors through.
+func csvErr(err error) error {
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		return permanentErr("parse dataset", err)
+	}
+	return err
+}
+
+// maxNDJSONLine bounds a single NDJSON record.
+const maxNDJSONLine = 1 << 20
+
+// ndjsonDecoder reads one JSON object per line, holding a timestamp and
+// metrics by name, with values written as in version 2 entries:
+//
+//	{"timestamp": 1714548600, "heartRate": 61, "sleepStage": "deep"}
+//	{"timestamp": 1714548660, "bloodPressure": {"systolic": 118, "diastolic": 76}}
+//
+// Timestamps may be numbers or strings. Blank lines are skipped.
+type ndjsonDecoder struct {
+	timestamps timestampFormat
+}
+
+func (n ndjsonDecoder) Decode(r io.Reader, yield func(DataEntry)) (Data, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+
+	d := Data{SchemaVersion: importedSchema}
+	for line := 1; sc.Scan(); line++ {
+		raw := bytes.TrimSpace(sc.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		e, err := n.entry(raw)
+		if err != nil {
+			return Data{}, permanentErr("parse dataset", fmt.Errorf("line %d: %w", line, err))
+		}
+		yield(e)
+	}
+	if err := sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return Data{}, permanentErr("parse dataset", fmt.Errorf("record longer than %d bytes", maxNDJSONLine))
+		}
+		return Data{}, err
+	}
+	return d, nil
+}
+
+func (n ndjsonDecoder) entry(raw []byte) (DataEntry, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			return DataEntry{}, fmt.Errorf("column %d: invalid JSON", se.Offset)
+		}
+		return DataEntry{}, errors.New("want a JSON object")
+	}
+	ts, ok := fields["timestamp"]
+	if !ok {
+		return DataEntry{}, errors.New("missing timestamp")
+	}
+	delete(fields, "timestamp")
+
+	var e DataEntry
+	var text string
+	if err := json.Unmarshal(ts, &text); err != nil {
+		text = string(ts) // a number
+	}
+	var err error
+	if e.Timestamp, err = n.timestamps.parse(text); err != nil {
+		return DataEntry{}, err
+	}
+
+	e.Values = make(map[MetricType]metricValue, len(fields))
+	for name, v := range fields {
+		def, ok := metricsByName[name]
+		if !ok {
+			return DataEntry{}, fmt.Errorf("unknown metric %q", name)
+		}
+		if e.Values[def.Type], err = def.parse(v); err != nil {
+			return DataEntry{}, err
+		}
+	}
+	return e, nil
+}
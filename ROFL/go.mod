@@ -5,6 +5,7 @@ go 1.24.1
 require (
 	github.com/ethereum/go-ethereum v1.15.11
 	github.com/ipfs/go-ipfs-api v0.7.0
+	github.com/zde37/pinata-go-sdk v1.0.0
 )
 
 require (
@@ -50,7 +51,6 @@ require (
 	github.com/supranational/blst v0.3.14 // indirect
 	github.com/tklauser/go-sysconf v0.3.12 // indirect
 	github.com/tklauser/numcpus v0.6.1 // indirect
-	github.com/zde37/pinata-go-sdk v1.0.0 // indirect
 	golang.org/x/crypto v0.35.0 // indirect
 	golang.org/x/sync v0.11.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
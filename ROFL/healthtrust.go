@@ -0,0 +1,1203 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package main
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// HealthTrustDataset is an auto generated low-level Go binding around an user-defined struct.
+type HealthTrustDataset struct {
+	IpfsHash          string
+	Gender            uint8
+	AgeRange          uint8
+	BmiCategory       uint8
+	ChronicConditions []uint8
+	HealthMetricTypes []uint8
+	Owner             common.Address
+	IsActive          bool
+}
+
+// HealthTrustOrder is an auto generated low-level Go binding around an user-defined struct.
+type HealthTrustOrder struct {
+	OrderId      *big.Int
+	DatasetId    *big.Int
+	Researcher   common.Address
+	Patient      common.Address
+	Amount       *big.Int
+	TokenAddress common.Address
+	Timestamp    *big.Int
+	Completed    bool
+}
+
+// HealthTrustMetaData contains all meta data concerning the HealthTrust contract.
+var HealthTrustMetaData = &bind.MetaData{
+	ABI: "[{\"inputs\":[{\"internalType\":\"bytes21\",\"name\":\"_roflAppID\",\"type\":\"bytes21\"}],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"datasetId\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"}],\"name\":\"DatasetSubmitted\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"datasetId\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"orderId\",\"type\":\"uint256\"}],\"name\":\"OrderCompleted\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"datasetId\",\"type\":\"uint256\"},{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"orderId\",\"type\":\"uint256\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"researcher\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\"}],\"name\":\"OrderCreated\",\"type\":\"event\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"datasetId\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"orderId\",\"type\":\"uint256\"},{\"internalType\":\"string\",\"name\":\"ipfsHash\",\"type\":\"string\"}],\"name\":\"completeOrder\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"datasetCount\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"datasets\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"ipfsHash\",\"type\":\"string\"},{\"internalType\":\"uint8\",\"name\":\"gender\",\"type\":\"uint8\"},{\"internalType\":\"uint8\",\"name\":\"ageRange\",\"type\":\"uint8\"},{\"internalType\":\"uint8\",\"name\":\"bmiCategory\",\"type\":\"uint8\"},{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"bool\",\"name\":\"isActive\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"getAllDatasets\",\"outputs\":[{\"components\":[{\"internalType\":\"string\",\"name\":\"ipfsHash\",\"type\":\"string\"},{\"internalType\":\"uint8\",\"name\":\"gender\",\"type\":\"uint8\"},{\"internalType\":\"uint8\",\"name\":\"ageRange\",\"type\":\"uint8\"},{\"internalType\":\"uint8\",\"name\":\"bmiCategory\",\"type\":\"uint8\"},{\"internalType\":\"uint8[]\",\"name\":\"chronicConditions\",\"type\":\"uint8[]\"},{\"internalType\":\"uint8[]\",\"name\":\"healthMetricTypes\",\"type\":\"uint8[]\"},{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"bool\",\"name\":\"isActive\",\"type\":\"bool\"}],\"internalType\":\"structHealthTrust.Dataset[]\",\"name\":\"out\",\"type\":\"tuple[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"}],\"name\":\"getDataset\",\"outputs\":[{\"components\":[{\"internalType\":\"string\",\"name\":\"ipfsHash\",\"type\":\"string\"},{\"internalType\":\"uint8\",\"name\":\"gender\",\"type\":\"uint8\"},{\"internalType\":\"uint8\",\"name\":\"ageRange\",\"type\":\"uint8\"},{\"internalType\":\"uint8\",\"name\":\"bmiCategory\",\"type\":\"uint8\"},{\"internalType\":\"uint8[]\",\"name\":\"chronicConditions\",\"type\":\"uint8[]\"},{\"internalType\":\"uint8[]\",\"name\":\"healthMetricTypes\",\"type\":\"uint8[]\"},{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"bool\",\"name\":\"isActive\",\"type\":\"bool\"}],\"internalType\":\"structHealthTrust.Dataset\",\"name\":\"\",\"type\":\"tuple\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"getDatasetCount\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"}],\"name\":\"getDatasetHash\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"getPubKey\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"datasetId\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"orderId\",\"type\":\"uint256\"}],\"name\":\"getStake\",\"outputs\":[{\"components\":[{\"internalType\":\"uint256\",\"name\":\"orderId\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"datasetId\",\"type\":\"uint256\"},{\"internalType\":\"address\",\"name\":\"researcher\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"patient\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\"},{\"internalType\":\"address\",\"name\":\"tokenAddress\",\"type\":\"address\"},{\"internalType\":\"uint40\",\"name\":\"timestamp\",\"type\":\"uint40\"},{\"internalType\":\"bool\",\"name\":\"completed\",\"type\":\"bool\"}],\"internalType\":\"structHealthTrust.Order\",\"name\":\"\",\"type\":\"tuple\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"orderCount\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"datasetId\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\"},{\"internalType\":\"address\",\"name\":\"tokenAddress\",\"type\":\"address\"}],\"name\":\"orderRequest\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"orderId\",\"type\":\"uint256\"}],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"orders\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"orderId\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"datasetId\",\"type\":\"uint256\"},{\"internalType\":\"address\",\"name\":\"researcher\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"patient\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\"},{\"internalType\":\"address\",\"name\":\"tokenAddress\",\"type\":\"address\"},{\"internalType\":\"uint40\",\"name\":\"timestamp\",\"type\":\"uint40\"},{\"internalType\":\"bool\",\"name\":\"completed\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"pubKey\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"resultRegistry\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"roflAppID\",\"outputs\":[{\"internalType\":\"bytes21\",\"name\":\"\",\"type\":\"bytes21\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"_pubKey\",\"type\":\"string\"}],\"name\":\"storePubKey\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"_ipfsHash\",\"type\":\"string\"},{\"internalType\":\"uint8\",\"name\":\"gender\",\"type\":\"uint8\"},{\"internalType\":\"uint8\",\"name\":\"ageRange\",\"type\":\"uint8\"},{\"internalType\":\"uint8\",\"name\":\"bmiCategory\",\"type\":\"uint8\"},{\"internalType\":\"uint8[]\",\"name\":\"chronicConditions\",\"type\":\"uint8[]\"},{\"internalType\":\"uint8[]\",\"name\":\"healthMetricTypes\",\"type\":\"uint8[]\"}],\"name\":\"submitDataset\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"datasetId\",\"type\":\"uint256\"}],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]",
+}
+
+// HealthTrustABI is the input ABI used to generate the binding from.
+// Deprecated: Use HealthTrustMetaData.ABI instead.
+var HealthTrustABI = HealthTrustMetaData.ABI
+
+// HealthTrust is an auto generated Go binding around an Ethereum contract.
+type HealthTrust struct {
+	HealthTrustCaller     // Read-only binding to the contract
+	HealthTrustTransactor // Write-only binding to the contract
+	HealthTrustFilterer   // Log filterer for contract events
+}
+
+// HealthTrustCaller is an auto generated read-only Go binding around an Ethereum contract.
+type HealthTrustCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// HealthTrustTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type HealthTrustTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// HealthTrustFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type HealthTrustFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// HealthTrustSession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type HealthTrustSession struct {
+	Contract     *HealthTrust      // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// HealthTrustCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type HealthTrustCallerSession struct {
+	Contract *HealthTrustCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts      // Call options to use throughout this session
+}
+
+// HealthTrustTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type HealthTrustTransactorSession struct {
+	Contract     *HealthTrustTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts      // Transaction auth options to use throughout this session
+}
+
+// HealthTrustRaw is an auto generated low-level Go binding around an Ethereum contract.
+type HealthTrustRaw struct {
+	Contract *HealthTrust // Generic contract binding to access the raw methods on
+}
+
+// HealthTrustCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type HealthTrustCallerRaw struct {
+	Contract *HealthTrustCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// HealthTrustTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type HealthTrustTransactorRaw struct {
+	Contract *HealthTrustTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewHealthTrust creates a new instance of HealthTrust, bound to a specific deployed contract.
+func NewHealthTrust(address common.Address, backend bind.ContractBackend) (*HealthTrust, error) {
+	contract, err := bindHealthTrust(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &HealthTrust{HealthTrustCaller: HealthTrustCaller{contract: contract}, HealthTrustTransactor: HealthTrustTransactor{contract: contract}, HealthTrustFilterer: HealthTrustFilterer{contract: contract}}, nil
+}
+
+// NewHealthTrustCaller creates a new read-only instance of HealthTrust, bound to a specific deployed contract.
+func NewHealthTrustCaller(address common.Address, caller bind.ContractCaller) (*HealthTrustCaller, error) {
+	contract, err := bindHealthTrust(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &HealthTrustCaller{contract: contract}, nil
+}
+
+// NewHealthTrustTransactor creates a new write-only instance of HealthTrust, bound to a specific deployed contract.
+func NewHealthTrustTransactor(address common.Address, transactor bind.ContractTransactor) (*HealthTrustTransactor, error) {
+	contract, err := bindHealthTrust(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &HealthTrustTransactor{contract: contract}, nil
+}
+
+// NewHealthTrustFilterer creates a new log filterer instance of HealthTrust, bound to a specific deployed contract.
+func NewHealthTrustFilterer(address common.Address, filterer bind.ContractFilterer) (*HealthTrustFilterer, error) {
+	contract, err := bindHealthTrust(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &HealthTrustFilterer{contract: contract}, nil
+}
+
+// bindHealthTrust binds a generic wrapper to an already deployed contract.
+func bindHealthTrust(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := HealthTrustMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_HealthTrust *HealthTrustRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _HealthTrust.Contract.HealthTrustCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_HealthTrust *HealthTrustRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _HealthTrust.Contract.HealthTrustTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_HealthTrust *HealthTrustRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _HealthTrust.Contract.HealthTrustTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_HealthTrust *HealthTrustCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _HealthTrust.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_HealthTrust *HealthTrustTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _HealthTrust.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_HealthTrust *HealthTrustTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _HealthTrust.Contract.contract.Transact(opts, method, params...)
+}
+
+// DatasetCount is a free data retrieval call binding the contract method 0x3c8935b9.
+//
+// Solidity: function datasetCount() view returns(uint256)
+func (_HealthTrust *HealthTrustCaller) DatasetCount(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "datasetCount")
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// DatasetCount is a free data retrieval call binding the contract method 0x3c8935b9.
+//
+// Solidity: function datasetCount() view returns(uint256)
+func (_HealthTrust *HealthTrustSession) DatasetCount() (*big.Int, error) {
+	return _HealthTrust.Contract.DatasetCount(&_HealthTrust.CallOpts)
+}
+
+// DatasetCount is a free data retrieval call binding the contract method 0x3c8935b9.
+//
+// Solidity: function datasetCount() view returns(uint256)
+func (_HealthTrust *HealthTrustCallerSession) DatasetCount() (*big.Int, error) {
+	return _HealthTrust.Contract.DatasetCount(&_HealthTrust.CallOpts)
+}
+
+// Datasets is a free data retrieval call binding the contract method 0x1cff85e1.
+//
+// Solidity: function datasets(uint256 ) view returns(string ipfsHash, uint8 gender, uint8 ageRange, uint8 bmiCategory, address owner, bool isActive)
+func (_HealthTrust *HealthTrustCaller) Datasets(opts *bind.CallOpts, arg0 *big.Int) (struct {
+	IpfsHash    string
+	Gender      uint8
+	AgeRange    uint8
+	BmiCategory uint8
+	Owner       common.Address
+	IsActive    bool
+}, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "datasets", arg0)
+
+	outstruct := new(struct {
+		IpfsHash    string
+		Gender      uint8
+		AgeRange    uint8
+		BmiCategory uint8
+		Owner       common.Address
+		IsActive    bool
+	})
+	if err != nil {
+		return *outstruct, err
+	}
+
+	outstruct.IpfsHash = *abi.ConvertType(out[0], new(string)).(*string)
+	outstruct.Gender = *abi.ConvertType(out[1], new(uint8)).(*uint8)
+	outstruct.AgeRange = *abi.ConvertType(out[2], new(uint8)).(*uint8)
+	outstruct.BmiCategory = *abi.ConvertType(out[3], new(uint8)).(*uint8)
+	outstruct.Owner = *abi.ConvertType(out[4], new(common.Address)).(*common.Address)
+	outstruct.IsActive = *abi.ConvertType(out[5], new(bool)).(*bool)
+
+	return *outstruct, err
+
+}
+
+// Datasets is a free data retrieval call binding the contract method 0x1cff85e1.
+//
+// Solidity: function datasets(uint256 ) view returns(string ipfsHash, uint8 gender, uint8 ageRange, uint8 bmiCategory, address owner, bool isActive)
+func (_HealthTrust *HealthTrustSession) Datasets(arg0 *big.Int) (struct {
+	IpfsHash    string
+	Gender      uint8
+	AgeRange    uint8
+	BmiCategory uint8
+	Owner       common.Address
+	IsActive    bool
+}, error) {
+	return _HealthTrust.Contract.Datasets(&_HealthTrust.CallOpts, arg0)
+}
+
+// Datasets is a free data retrieval call binding the contract method 0x1cff85e1.
+//
+// Solidity: function datasets(uint256 ) view returns(string ipfsHash, uint8 gender, uint8 ageRange, uint8 bmiCategory, address owner, bool isActive)
+func (_HealthTrust *HealthTrustCallerSession) Datasets(arg0 *big.Int) (struct {
+	IpfsHash    string
+	Gender      uint8
+	AgeRange    uint8
+	BmiCategory uint8
+	Owner       common.Address
+	IsActive    bool
+}, error) {
+	return _HealthTrust.Contract.Datasets(&_HealthTrust.CallOpts, arg0)
+}
+
+// GetAllDatasets is a free data retrieval call binding the contract method 0x4d72cd12.
+//
+// Solidity: function getAllDatasets() view returns((string,uint8,uint8,uint8,uint8[],uint8[],address,bool)[] out)
+func (_HealthTrust *HealthTrustCaller) GetAllDatasets(opts *bind.CallOpts) ([]HealthTrustDataset, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "getAllDatasets")
+
+	if err != nil {
+		return *new([]HealthTrustDataset), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([]HealthTrustDataset)).(*[]HealthTrustDataset)
+
+	return out0, err
+
+}
+
+// GetAllDatasets is a free data retrieval call binding the contract method 0x4d72cd12.
+//
+// Solidity: function getAllDatasets() view returns((string,uint8,uint8,uint8,uint8[],uint8[],address,bool)[] out)
+func (_HealthTrust *HealthTrustSession) GetAllDatasets() ([]HealthTrustDataset, error) {
+	return _HealthTrust.Contract.GetAllDatasets(&_HealthTrust.CallOpts)
+}
+
+// GetAllDatasets is a free data retrieval call binding the contract method 0x4d72cd12.
+//
+// Solidity: function getAllDatasets() view returns((string,uint8,uint8,uint8,uint8[],uint8[],address,bool)[] out)
+func (_HealthTrust *HealthTrustCallerSession) GetAllDatasets() ([]HealthTrustDataset, error) {
+	return _HealthTrust.Contract.GetAllDatasets(&_HealthTrust.CallOpts)
+}
+
+// GetDataset is a free data retrieval call binding the contract method 0xee004221.
+//
+// Solidity: function getDataset(uint256 id) view returns((string,uint8,uint8,uint8,uint8[],uint8[],address,bool))
+func (_HealthTrust *HealthTrustCaller) GetDataset(opts *bind.CallOpts, id *big.Int) (HealthTrustDataset, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "getDataset", id)
+
+	if err != nil {
+		return *new(HealthTrustDataset), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(HealthTrustDataset)).(*HealthTrustDataset)
+
+	return out0, err
+
+}
+
+// GetDataset is a free data retrieval call binding the contract method 0xee004221.
+//
+// Solidity: function getDataset(uint256 id) view returns((string,uint8,uint8,uint8,uint8[],uint8[],address,bool))
+func (_HealthTrust *HealthTrustSession) GetDataset(id *big.Int) (HealthTrustDataset, error) {
+	return _HealthTrust.Contract.GetDataset(&_HealthTrust.CallOpts, id)
+}
+
+// GetDataset is a free data retrieval call binding the contract method 0xee004221.
+//
+// Solidity: function getDataset(uint256 id) view returns((string,uint8,uint8,uint8,uint8[],uint8[],address,bool))
+func (_HealthTrust *HealthTrustCallerSession) GetDataset(id *big.Int) (HealthTrustDataset, error) {
+	return _HealthTrust.Contract.GetDataset(&_HealthTrust.CallOpts, id)
+}
+
+// GetDatasetCount is a free data retrieval call binding the contract method 0x2f5201bd.
+//
+// Solidity: function getDatasetCount() view returns(uint256)
+func (_HealthTrust *HealthTrustCaller) GetDatasetCount(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "getDatasetCount")
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// GetDatasetCount is a free data retrieval call binding the contract method 0x2f5201bd.
+//
+// Solidity: function getDatasetCount() view returns(uint256)
+func (_HealthTrust *HealthTrustSession) GetDatasetCount() (*big.Int, error) {
+	return _HealthTrust.Contract.GetDatasetCount(&_HealthTrust.CallOpts)
+}
+
+// GetDatasetCount is a free data retrieval call binding the contract method 0x2f5201bd.
+//
+// Solidity: function getDatasetCount() view returns(uint256)
+func (_HealthTrust *HealthTrustCallerSession) GetDatasetCount() (*big.Int, error) {
+	return _HealthTrust.Contract.GetDatasetCount(&_HealthTrust.CallOpts)
+}
+
+// GetDatasetHash is a free data retrieval call binding the contract method 0xa467276c.
+//
+// Solidity: function getDatasetHash(uint256 id) view returns(string)
+func (_HealthTrust *HealthTrustCaller) GetDatasetHash(opts *bind.CallOpts, id *big.Int) (string, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "getDatasetHash", id)
+
+	if err != nil {
+		return *new(string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+
+	return out0, err
+
+}
+
+// GetDatasetHash is a free data retrieval call binding the contract method 0xa467276c.
+//
+// Solidity: function getDatasetHash(uint256 id) view returns(string)
+func (_HealthTrust *HealthTrustSession) GetDatasetHash(id *big.Int) (string, error) {
+	return _HealthTrust.Contract.GetDatasetHash(&_HealthTrust.CallOpts, id)
+}
+
+// GetDatasetHash is a free data retrieval call binding the contract method 0xa467276c.
+//
+// Solidity: function getDatasetHash(uint256 id) view returns(string)
+func (_HealthTrust *HealthTrustCallerSession) GetDatasetHash(id *big.Int) (string, error) {
+	return _HealthTrust.Contract.GetDatasetHash(&_HealthTrust.CallOpts, id)
+}
+
+// GetPubKey is a free data retrieval call binding the contract method 0x4ad02ef1.
+//
+// Solidity: function getPubKey() view returns(string)
+func (_HealthTrust *HealthTrustCaller) GetPubKey(opts *bind.CallOpts) (string, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "getPubKey")
+
+	if err != nil {
+		return *new(string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+
+	return out0, err
+
+}
+
+// GetPubKey is a free data retrieval call binding the contract method 0x4ad02ef1.
+//
+// Solidity: function getPubKey() view returns(string)
+func (_HealthTrust *HealthTrustSession) GetPubKey() (string, error) {
+	return _HealthTrust.Contract.GetPubKey(&_HealthTrust.CallOpts)
+}
+
+// GetPubKey is a free data retrieval call binding the contract method 0x4ad02ef1.
+//
+// Solidity: function getPubKey() view returns(string)
+func (_HealthTrust *HealthTrustCallerSession) GetPubKey() (string, error) {
+	return _HealthTrust.Contract.GetPubKey(&_HealthTrust.CallOpts)
+}
+
+// GetStake is a free data retrieval call binding the contract method 0xa0befa94.
+//
+// Solidity: function getStake(uint256 datasetId, uint256 orderId) view returns((uint256,uint256,address,address,uint256,address,uint40,bool))
+func (_HealthTrust *HealthTrustCaller) GetStake(opts *bind.CallOpts, datasetId *big.Int, orderId *big.Int) (HealthTrustOrder, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "getStake", datasetId, orderId)
+
+	if err != nil {
+		return *new(HealthTrustOrder), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(HealthTrustOrder)).(*HealthTrustOrder)
+
+	return out0, err
+
+}
+
+// GetStake is a free data retrieval call binding the contract method 0xa0befa94.
+//
+// Solidity: function getStake(uint256 datasetId, uint256 orderId) view returns((uint256,uint256,address,address,uint256,address,uint40,bool))
+func (_HealthTrust *HealthTrustSession) GetStake(datasetId *big.Int, orderId *big.Int) (HealthTrustOrder, error) {
+	return _HealthTrust.Contract.GetStake(&_HealthTrust.CallOpts, datasetId, orderId)
+}
+
+// GetStake is a free data retrieval call binding the contract method 0xa0befa94.
+//
+// Solidity: function getStake(uint256 datasetId, uint256 orderId) view returns((uint256,uint256,address,address,uint256,address,uint40,bool))
+func (_HealthTrust *HealthTrustCallerSession) GetStake(datasetId *big.Int, orderId *big.Int) (HealthTrustOrder, error) {
+	return _HealthTrust.Contract.GetStake(&_HealthTrust.CallOpts, datasetId, orderId)
+}
+
+// OrderCount is a free data retrieval call binding the contract method 0x2453ffa8.
+//
+// Solidity: function orderCount() view returns(uint256)
+func (_HealthTrust *HealthTrustCaller) OrderCount(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "orderCount")
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// OrderCount is a free data retrieval call binding the contract method 0x2453ffa8.
+//
+// Solidity: function orderCount() view returns(uint256)
+func (_HealthTrust *HealthTrustSession) OrderCount() (*big.Int, error) {
+	return _HealthTrust.Contract.OrderCount(&_HealthTrust.CallOpts)
+}
+
+// OrderCount is a free data retrieval call binding the contract method 0x2453ffa8.
+//
+// Solidity: function orderCount() view returns(uint256)
+func (_HealthTrust *HealthTrustCallerSession) OrderCount() (*big.Int, error) {
+	return _HealthTrust.Contract.OrderCount(&_HealthTrust.CallOpts)
+}
+
+// Orders is a free data retrieval call binding the contract method 0xb94a000f.
+//
+// Solidity: function orders(uint256 , uint256 ) view returns(uint256 orderId, uint256 datasetId, address researcher, address patient, uint256 amount, address tokenAddress, uint40 timestamp, bool completed)
+func (_HealthTrust *HealthTrustCaller) Orders(opts *bind.CallOpts, arg0 *big.Int, arg1 *big.Int) (struct {
+	OrderId      *big.Int
+	DatasetId    *big.Int
+	Researcher   common.Address
+	Patient      common.Address
+	Amount       *big.Int
+	TokenAddress common.Address
+	Timestamp    *big.Int
+	Completed    bool
+}, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "orders", arg0, arg1)
+
+	outstruct := new(struct {
+		OrderId      *big.Int
+		DatasetId    *big.Int
+		Researcher   common.Address
+		Patient      common.Address
+		Amount       *big.Int
+		TokenAddress common.Address
+		Timestamp    *big.Int
+		Completed    bool
+	})
+	if err != nil {
+		return *outstruct, err
+	}
+
+	outstruct.OrderId = *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+	outstruct.DatasetId = *abi.ConvertType(out[1], new(*big.Int)).(**big.Int)
+	outstruct.Researcher = *abi.ConvertType(out[2], new(common.Address)).(*common.Address)
+	outstruct.Patient = *abi.ConvertType(out[3], new(common.Address)).(*common.Address)
+	outstruct.Amount = *abi.ConvertType(out[4], new(*big.Int)).(**big.Int)
+	outstruct.TokenAddress = *abi.ConvertType(out[5], new(common.Address)).(*common.Address)
+	outstruct.Timestamp = *abi.ConvertType(out[6], new(*big.Int)).(**big.Int)
+	outstruct.Completed = *abi.ConvertType(out[7], new(bool)).(*bool)
+
+	return *outstruct, err
+
+}
+
+// Orders is a free data retrieval call binding the contract method 0xb94a000f.
+//
+// Solidity: function orders(uint256 , uint256 ) view returns(uint256 orderId, uint256 datasetId, address researcher, address patient, uint256 amount, address tokenAddress, uint40 timestamp, bool completed)
+func (_HealthTrust *HealthTrustSession) Orders(arg0 *big.Int, arg1 *big.Int) (struct {
+	OrderId      *big.Int
+	DatasetId    *big.Int
+	Researcher   common.Address
+	Patient      common.Address
+	Amount       *big.Int
+	TokenAddress common.Address
+	Timestamp    *big.Int
+	Completed    bool
+}, error) {
+	return _HealthTrust.Contract.Orders(&_HealthTrust.CallOpts, arg0, arg1)
+}
+
+// Orders is a free data retrieval call binding the contract method 0xb94a000f.
+//
+// Solidity: function orders(uint256 , uint256 ) view returns(uint256 orderId, uint256 datasetId, address researcher, address patient, uint256 amount, address tokenAddress, uint40 timestamp, bool completed)
+func (_HealthTrust *HealthTrustCallerSession) Orders(arg0 *big.Int, arg1 *big.Int) (struct {
+	OrderId      *big.Int
+	DatasetId    *big.Int
+	Researcher   common.Address
+	Patient      common.Address
+	Amount       *big.Int
+	TokenAddress common.Address
+	Timestamp    *big.Int
+	Completed    bool
+}, error) {
+	return _HealthTrust.Contract.Orders(&_HealthTrust.CallOpts, arg0, arg1)
+}
+
+// PubKey is a free data retrieval call binding the contract method 0xac2a5dfd.
+//
+// Solidity: function pubKey() view returns(string)
+func (_HealthTrust *HealthTrustCaller) PubKey(opts *bind.CallOpts) (string, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "pubKey")
+
+	if err != nil {
+		return *new(string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+
+	return out0, err
+
+}
+
+// PubKey is a free data retrieval call binding the contract method 0xac2a5dfd.
+//
+// Solidity: function pubKey() view returns(string)
+func (_HealthTrust *HealthTrustSession) PubKey() (string, error) {
+	return _HealthTrust.Contract.PubKey(&_HealthTrust.CallOpts)
+}
+
+// PubKey is a free data retrieval call binding the contract method 0xac2a5dfd.
+//
+// Solidity: function pubKey() view returns(string)
+func (_HealthTrust *HealthTrustCallerSession) PubKey() (string, error) {
+	return _HealthTrust.Contract.PubKey(&_HealthTrust.CallOpts)
+}
+
+// ResultRegistry is a free data retrieval call binding the contract method 0xae3fa12d.
+//
+// Solidity: function resultRegistry(uint256 ) view returns(string)
+func (_HealthTrust *HealthTrustCaller) ResultRegistry(opts *bind.CallOpts, arg0 *big.Int) (string, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "resultRegistry", arg0)
+
+	if err != nil {
+		return *new(string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+
+	return out0, err
+
+}
+
+// ResultRegistry is a free data retrieval call binding the contract method 0xae3fa12d.
+//
+// Solidity: function resultRegistry(uint256 ) view returns(string)
+func (_HealthTrust *HealthTrustSession) ResultRegistry(arg0 *big.Int) (string, error) {
+	return _HealthTrust.Contract.ResultRegistry(&_HealthTrust.CallOpts, arg0)
+}
+
+// ResultRegistry is a free data retrieval call binding the contract method 0xae3fa12d.
+//
+// Solidity: function resultRegistry(uint256 ) view returns(string)
+func (_HealthTrust *HealthTrustCallerSession) ResultRegistry(arg0 *big.Int) (string, error) {
+	return _HealthTrust.Contract.ResultRegistry(&_HealthTrust.CallOpts, arg0)
+}
+
+// RoflAppID is a free data retrieval call binding the contract method 0xefae93df.
+//
+// Solidity: function roflAppID() view returns(bytes21)
+func (_HealthTrust *HealthTrustCaller) RoflAppID(opts *bind.CallOpts) ([21]byte, error) {
+	var out []interface{}
+	err := _HealthTrust.contract.Call(opts, &out, "roflAppID")
+
+	if err != nil {
+		return *new([21]byte), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([21]byte)).(*[21]byte)
+
+	return out0, err
+
+}
+
+// RoflAppID is a free data retrieval call binding the contract method 0xefae93df.
+//
+// Solidity: function roflAppID() view returns(bytes21)
+func (_HealthTrust *HealthTrustSession) RoflAppID() ([21]byte, error) {
+	return _HealthTrust.Contract.RoflAppID(&_HealthTrust.CallOpts)
+}
+
+// RoflAppID is a free data retrieval call binding the contract method 0xefae93df.
+//
+// Solidity: function roflAppID() view returns(bytes21)
+func (_HealthTrust *HealthTrustCallerSession) RoflAppID() ([21]byte, error) {
+	return _HealthTrust.Contract.RoflAppID(&_HealthTrust.CallOpts)
+}
+
+// CompleteOrder is a paid mutator transaction binding the contract method 0x7824917c.
+//
+// Solidity: function completeOrder(uint256 datasetId, uint256 orderId, string ipfsHash) returns()
+func (_HealthTrust *HealthTrustTransactor) CompleteOrder(opts *bind.TransactOpts, datasetId *big.Int, orderId *big.Int, ipfsHash string) (*types.Transaction, error) {
+	return _HealthTrust.contract.Transact(opts, "completeOrder", datasetId, orderId, ipfsHash)
+}
+
+// CompleteOrder is a paid mutator transaction binding the contract method 0x7824917c.
+//
+// Solidity: function completeOrder(uint256 datasetId, uint256 orderId, string ipfsHash) returns()
+func (_HealthTrust *HealthTrustSession) CompleteOrder(datasetId *big.Int, orderId *big.Int, ipfsHash string) (*types.Transaction, error) {
+	return _HealthTrust.Contract.CompleteOrder(&_HealthTrust.TransactOpts, datasetId, orderId, ipfsHash)
+}
+
+// CompleteOrder is a paid mutator transaction binding the contract method 0x7824917c.
+//
+// Solidity: function completeOrder(uint256 datasetId, uint256 orderId, string ipfsHash) returns()
+func (_HealthTrust *HealthTrustTransactorSession) CompleteOrder(datasetId *big.Int, orderId *big.Int, ipfsHash string) (*types.Transaction, error) {
+	return _HealthTrust.Contract.CompleteOrder(&_HealthTrust.TransactOpts, datasetId, orderId, ipfsHash)
+}
+
+// OrderRequest is a paid mutator transaction binding the contract method 0x19d89369.
+//
+// Solidity: function orderRequest(uint256 datasetId, uint256 amount, address tokenAddress) returns(uint256 orderId)
+func (_HealthTrust *HealthTrustTransactor) OrderRequest(opts *bind.TransactOpts, datasetId *big.Int, amount *big.Int, tokenAddress common.Address) (*types.Transaction, error) {
+	return _HealthTrust.contract.Transact(opts, "orderRequest", datasetId, amount, tokenAddress)
+}
+
+// OrderRequest is a paid mutator transaction binding the contract method 0x19d89369.
+//
+// Solidity: function orderRequest(uint256 datasetId, uint256 amount, address tokenAddress) returns(uint256 orderId)
+func (_HealthTrust *HealthTrustSession) OrderRequest(datasetId *big.Int, amount *big.Int, tokenAddress common.Address) (*types.Transaction, error) {
+	return _HealthTrust.Contract.OrderRequest(&_HealthTrust.TransactOpts, datasetId, amount, tokenAddress)
+}
+
+// OrderRequest is a paid mutator transaction binding the contract method 0x19d89369.
+//
+// Solidity: function orderRequest(uint256 datasetId, uint256 amount, address tokenAddress) returns(uint256 orderId)
+func (_HealthTrust *HealthTrustTransactorSession) OrderRequest(datasetId *big.Int, amount *big.Int, tokenAddress common.Address) (*types.Transaction, error) {
+	return _HealthTrust.Contract.OrderRequest(&_HealthTrust.TransactOpts, datasetId, amount, tokenAddress)
+}
+
+// StorePubKey is a paid mutator transaction binding the contract method 0xfbf44162.
+//
+// Solidity: function storePubKey(string _pubKey) returns()
+func (_HealthTrust *HealthTrustTransactor) StorePubKey(opts *bind.TransactOpts, _pubKey string) (*types.Transaction, error) {
+	return _HealthTrust.contract.Transact(opts, "storePubKey", _pubKey)
+}
+
+// StorePubKey is a paid mutator transaction binding the contract method 0xfbf44162.
+//
+// Solidity: function storePubKey(string _pubKey) returns()
+func (_HealthTrust *HealthTrustSession) StorePubKey(_pubKey string) (*types.Transaction, error) {
+	return _HealthTrust.Contract.StorePubKey(&_HealthTrust.TransactOpts, _pubKey)
+}
+
+// StorePubKey is a paid mutator transaction binding the contract method 0xfbf44162.
+//
+// Solidity: function storePubKey(string _pubKey) returns()
+func (_HealthTrust *HealthTrustTransactorSession) StorePubKey(_pubKey string) (*types.Transaction, error) {
+	return _HealthTrust.Contract.StorePubKey(&_HealthTrust.TransactOpts, _pubKey)
+}
+
+// SubmitDataset is a paid mutator transaction binding the contract method 0x50bc9c8f.
+//
+// Solidity: function submitDataset(string _ipfsHash, uint8 gender, uint8 ageRange, uint8 bmiCategory, uint8[] chronicConditions, uint8[] healthMetricTypes) returns(uint256 datasetId)
+func (_HealthTrust *HealthTrustTransactor) SubmitDataset(opts *bind.TransactOpts, _ipfsHash string, gender uint8, ageRange uint8, bmiCategory uint8, chronicConditions []uint8, healthMetricTypes []uint8) (*types.Transaction, error) {
+	return _HealthTrust.contract.Transact(opts, "submitDataset", _ipfsHash, gender, ageRange, bmiCategory, chronicConditions, healthMetricTypes)
+}
+
+// SubmitDataset is a paid mutator transaction binding the contract method 0x50bc9c8f.
+//
+// Solidity: function submitDataset(string _ipfsHash, uint8 gender, uint8 ageRange, uint8 bmiCategory, uint8[] chronicConditions, uint8[] healthMetricTypes) returns(uint256 datasetId)
+func (_HealthTrust *HealthTrustSession) SubmitDataset(_ipfsHash string, gender uint8, ageRange uint8, bmiCategory uint8, chronicConditions []uint8, healthMetricTypes []uint8) (*types.Transaction, error) {
+	return _HealthTrust.Contract.SubmitDataset(&_HealthTrust.TransactOpts, _ipfsHash, gender, ageRange, bmiCategory, chronicConditions, healthMetricTypes)
+}
+
+// SubmitDataset is a paid mutator transaction binding the contract method 0x50bc9c8f.
+//
+// Solidity: function submitDataset(string _ipfsHash, uint8 gender, uint8 ageRange, uint8 bmiCategory, uint8[] chronicConditions, uint8[] healthMetricTypes) returns(uint256 datasetId)
+func (_HealthTrust *HealthTrustTransactorSession) SubmitDataset(_ipfsHash string, gender uint8, ageRange uint8, bmiCategory uint8, chronicConditions []uint8, healthMetricTypes []uint8) (*types.Transaction, error) {
+	return _HealthTrust.Contract.SubmitDataset(&_HealthTrust.TransactOpts, _ipfsHash, gender, ageRange, bmiCategory, chronicConditions, healthMetricTypes)
+}
+
+// HealthTrustDatasetSubmittedIterator is returned from FilterDatasetSubmitted and is used to iterate over the raw logs and unpacked data for DatasetSubmitted events raised by the HealthTrust contract.
+type HealthTrustDatasetSubmittedIterator struct {
+	Event *HealthTrustDatasetSubmitted // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *HealthTrustDatasetSubmittedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(HealthTrustDatasetSubmitted)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(HealthTrustDatasetSubmitted)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *HealthTrustDatasetSubmittedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *HealthTrustDatasetSubmittedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// HealthTrustDatasetSubmitted represents a DatasetSubmitted event raised by the HealthTrust contract.
+type HealthTrustDatasetSubmitted struct {
+	DatasetId *big.Int
+	Owner     common.Address
+	Raw       types.Log // Blockchain specific contextual infos
+}
+
+// FilterDatasetSubmitted is a free log retrieval operation binding the contract event 0x54dadb8db4e7ed3a3571d8ba2c601a85cb4f4f2ca8ae678d1c6d2c580579bee1.
+//
+// Solidity: event DatasetSubmitted(uint256 datasetId, address owner)
+func (_HealthTrust *HealthTrustFilterer) FilterDatasetSubmitted(opts *bind.FilterOpts) (*HealthTrustDatasetSubmittedIterator, error) {
+
+	logs, sub, err := _HealthTrust.contract.FilterLogs(opts, "DatasetSubmitted")
+	if err != nil {
+		return nil, err
+	}
+	return &HealthTrustDatasetSubmittedIterator{contract: _HealthTrust.contract, event: "DatasetSubmitted", logs: logs, sub: sub}, nil
+}
+
+// WatchDatasetSubmitted is a free log subscription operation binding the contract event 0x54dadb8db4e7ed3a3571d8ba2c601a85cb4f4f2ca8ae678d1c6d2c580579bee1.
+//
+// Solidity: event DatasetSubmitted(uint256 datasetId, address owner)
+func (_HealthTrust *HealthTrustFilterer) WatchDatasetSubmitted(opts *bind.WatchOpts, sink chan<- *HealthTrustDatasetSubmitted) (event.Subscription, error) {
+
+	logs, sub, err := _HealthTrust.contract.WatchLogs(opts, "DatasetSubmitted")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(HealthTrustDatasetSubmitted)
+				if err := _HealthTrust.contract.UnpackLog(event, "DatasetSubmitted", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseDatasetSubmitted is a log parse operation binding the contract event 0x54dadb8db4e7ed3a3571d8ba2c601a85cb4f4f2ca8ae678d1c6d2c580579bee1.
+//
+// Solidity: event DatasetSubmitted(uint256 datasetId, address owner)
+func (_HealthTrust *HealthTrustFilterer) ParseDatasetSubmitted(log types.Log) (*HealthTrustDatasetSubmitted, error) {
+	event := new(HealthTrustDatasetSubmitted)
+	if err := _HealthTrust.contract.UnpackLog(event, "DatasetSubmitted", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// HealthTrustOrderCompletedIterator is returned from FilterOrderCompleted and is used to iterate over the raw logs and unpacked data for OrderCompleted events raised by the HealthTrust contract.
+type HealthTrustOrderCompletedIterator struct {
+	Event *HealthTrustOrderCompleted // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *HealthTrustOrderCompletedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(HealthTrustOrderCompleted)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(HealthTrustOrderCompleted)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *HealthTrustOrderCompletedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *HealthTrustOrderCompletedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// HealthTrustOrderCompleted represents a OrderCompleted event raised by the HealthTrust contract.
+type HealthTrustOrderCompleted struct {
+	DatasetId *big.Int
+	OrderId   *big.Int
+	Raw       types.Log // Blockchain specific contextual infos
+}
+
+// FilterOrderCompleted is a free log retrieval operation binding the contract event 0x8018cc3f9db78a02ed6d3210f982aa968ce2a26acb738b0917febbcdd47b350c.
+//
+// Solidity: event OrderCompleted(uint256 datasetId, uint256 orderId)
+func (_HealthTrust *HealthTrustFilterer) FilterOrderCompleted(opts *bind.FilterOpts) (*HealthTrustOrderCompletedIterator, error) {
+
+	logs, sub, err := _HealthTrust.contract.FilterLogs(opts, "OrderCompleted")
+	if err != nil {
+		return nil, err
+	}
+	return &HealthTrustOrderCompletedIterator{contract: _HealthTrust.contract, event: "OrderCompleted", logs: logs, sub: sub}, nil
+}
+
+// WatchOrderCompleted is a free log subscription operation binding the contract event 0x8018cc3f9db78a02ed6d3210f982aa968ce2a26acb738b0917febbcdd47b350c.
+//
+// Solidity: event OrderCompleted(uint256 datasetId, uint256 orderId)
+func (_HealthTrust *HealthTrustFilterer) WatchOrderCompleted(opts *bind.WatchOpts, sink chan<- *HealthTrustOrderCompleted) (event.Subscription, error) {
+
+	logs, sub, err := _HealthTrust.contract.WatchLogs(opts, "OrderCompleted")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(HealthTrustOrderCompleted)
+				if err := _HealthTrust.contract.UnpackLog(event, "OrderCompleted", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseOrderCompleted is a log parse operation binding the contract event 0x8018cc3f9db78a02ed6d3210f982aa968ce2a26acb738b0917febbcdd47b350c.
+//
+// Solidity: event OrderCompleted(uint256 datasetId, uint256 orderId)
+func (_HealthTrust *HealthTrustFilterer) ParseOrderCompleted(log types.Log) (*HealthTrustOrderCompleted, error) {
+	event := new(HealthTrustOrderCompleted)
+	if err := _HealthTrust.contract.UnpackLog(event, "OrderCompleted", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// HealthTrustOrderCreatedIterator is returned from FilterOrderCreated and is used to iterate over the raw logs and unpacked data for OrderCreated events raised by the HealthTrust contract.
+type HealthTrustOrderCreatedIterator struct {
+	Event *HealthTrustOrderCreated // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *HealthTrustOrderCreatedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(HealthTrustOrderCreated)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(HealthTrustOrderCreated)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *HealthTrustOrderCreatedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *HealthTrustOrderCreatedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// HealthTrustOrderCreated represents a OrderCreated event raised by the HealthTrust contract.
+type HealthTrustOrderCreated struct {
+	DatasetId  *big.Int
+	OrderId    *big.Int
+	Researcher common.Address
+	Amount     *big.Int
+	Raw        types.Log // Blockchain specific contextual infos
+}
+
+// FilterOrderCreated is a free log retrieval operation binding the contract event 0xab45dcf8e0b46d291b98e53c6f1c6774dc32e2436543291f7bfbb3e3c48b2f9f.
+//
+// Solidity: event OrderCreated(uint256 indexed datasetId, uint256 indexed orderId, address indexed researcher, uint256 amount)
+func (_HealthTrust *HealthTrustFilterer) FilterOrderCreated(opts *bind.FilterOpts, datasetId []*big.Int, orderId []*big.Int, researcher []common.Address) (*HealthTrustOrderCreatedIterator, error) {
+
+	var datasetIdRule []interface{}
+	for _, datasetIdItem := range datasetId {
+		datasetIdRule = append(datasetIdRule, datasetIdItem)
+	}
+	var orderIdRule []interface{}
+	for _, orderIdItem := range orderId {
+		orderIdRule = append(orderIdRule, orderIdItem)
+	}
+	var researcherRule []interface{}
+	for _, researcherItem := range researcher {
+		researcherRule = append(researcherRule, researcherItem)
+	}
+
+	logs, sub, err := _HealthTrust.contract.FilterLogs(opts, "OrderCreated", datasetIdRule, orderIdRule, researcherRule)
+	if err != nil {
+		return nil, err
+	}
+	return &HealthTrustOrderCreatedIterator{contract: _HealthTrust.contract, event: "OrderCreated", logs: logs, sub: sub}, nil
+}
+
+// WatchOrderCreated is a free log subscription operation binding the contract event 0xab45dcf8e0b46d291b98e53c6f1c6774dc32e2436543291f7bfbb3e3c48b2f9f.
+//
+// Solidity: event OrderCreated(uint256 indexed datasetId, uint256 indexed orderId, address indexed researcher, uint256 amount)
+func (_HealthTrust *HealthTrustFilterer) WatchOrderCreated(opts *bind.WatchOpts, sink chan<- *HealthTrustOrderCreated, datasetId []*big.Int, orderId []*big.Int, researcher []common.Address) (event.Subscription, error) {
+
+	var datasetIdRule []interface{}
+	for _, datasetIdItem := range datasetId {
+		datasetIdRule = append(datasetIdRule, datasetIdItem)
+	}
+	var orderIdRule []interface{}
+	for _, orderIdItem := range orderId {
+		orderIdRule = append(orderIdRule, orderIdItem)
+	}
+	var researcherRule []interface{}
+	for _, researcherItem := range researcher {
+		researcherRule = append(researcherRule, researcherItem)
+	}
+
+	logs, sub, err := _HealthTrust.contract.WatchLogs(opts, "OrderCreated", datasetIdRule, orderIdRule, researcherRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(HealthTrustOrderCreated)
+				if err := _HealthTrust.contract.UnpackLog(event, "OrderCreated", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseOrderCreated is a log parse operation binding the contract event 0xab45dcf8e0b46d291b98e53c6f1c6774dc32e2436543291f7bfbb3e3c48b2f9f.
+//
+// Solidity: event OrderCreated(uint256 indexed datasetId, uint256 indexed orderId, address indexed researcher, uint256 amount)
+func (_HealthTrust *HealthTrustFilterer) ParseOrderCreated(log types.Log) (*HealthTrustOrderCreated, error) {
+	event := new(HealthTrustOrderCreated)
+	if err := _HealthTrust.contract.UnpackLog(event, "OrderCreated", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
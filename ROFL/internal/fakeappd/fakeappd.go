@@ -0,0 +1,175 @@
+// Package fakeappd implements the parts of the rofl-appd REST API the worker
+// uses, transaction submission and key generation, for development outside
+// ROFL and for tests. cmd fakeappd serves it
+// on a unix socket.
+package fakeappd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Hex-encoded CBOR call results, as returned by the real daemon.
+const (
+	CallOK       = "a1626f6b40"                                                                         // {"ok": h''}
+	CallReverted = "a1646661696ca3666d6f64756c656365766d64636f646508676d657373616765687265766572746564" // {"fail": {"module": "evm", "code": 8, "message": "reverted"}}
+)
+
+// Chain executes the transactions sign-submit is asked for.
+type Chain interface {
+	Submit(ctx context.Context, to common.Address, gasLimit uint64, value *big.Int, data []byte) (*types.Receipt, error)
+}
+
+type signSubmitRequest struct {
+	Tx struct {
+		Kind string `json:"kind"`
+		Data struct {
+			GasLimit uint64 `json:"gas_limit"`
+			To       string `json:"to"`
+			Value    uint64 `json:"value"`
+			Data     string `json:"data"`
+		} `json:"data"`
+	} `json:"tx"`
+	Encrypt bool `json:"encrypt"`
+}
+
+// NewHandler returns the daemon's HTTP API backed by chain.
+func NewHandler(chain Chain) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /rofl/v1/tx/sign-submit", func(w http.ResponseWriter, r *http.Request) {
+		signSubmit(chain, w, r)
+	})
+	mux.HandleFunc("POST /rofl/v1/keys/generate", generateKey)
+	return mux
+}
+
+type keyRequest struct {
+	KeyID string `json:"key_id"`
+	Kind  string `json:"kind"`
+}
+
+// generateKey derives the key from key_id alone, so like the real daemon it
+// returns the same key for the same id across restarts. Anyone can derive
+// these keys: they are for development only.
+func generateKey(w http.ResponseWriter, r *http.Request) {
+	var req keyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Kind != "secp256k1" {
+		http.Error(w, fmt.Sprintf("unsupported key kind %q", req.Kind), http.StatusBadRequest)
+		return
+	}
+	if req.KeyID == "" {
+		http.Error(w, "missing key_id", http.StatusBadRequest)
+		return
+	}
+
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte("fakeappd secp256k1 key"), []byte(req.KeyID)))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"key": hex.EncodeToString(crypto.FromECDSA(key))})
+}
+
+func signSubmit(chain Chain, w http.ResponseWriter, r *http.Request) {
+	var req signSubmitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Tx.Kind != "eth" {
+		http.Error(w, fmt.Sprintf("unsupported tx kind %q", req.Tx.Kind), http.StatusBadRequest)
+		return
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(req.Tx.Data.Data, "0x"))
+	if err != nil {
+		http.Error(w, "invalid data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := chain.Submit(r.Context(), common.HexToAddress(req.Tx.Data.To), req.Tx.Data.GasLimit, new(big.Int).SetUint64(req.Tx.Data.Value), data)
+	if err != nil {
+		log.Printf("sign-submit failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result := CallOK
+	if receipt.Status == types.ReceiptStatusFailed {
+		result = CallReverted
+	}
+	log.Printf("transaction %s mined with status %d", receipt.TxHash.Hex(), receipt.Status)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"data": result})
+}
+
+// RPCChain signs transactions with a local key instead of the app key and
+// sends them to a JSON-RPC endpoint.
+type RPCChain struct {
+	mu      sync.Mutex // one transaction at a time, like the real daemon
+	cli     *ethclient.Client
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+}
+
+// NewRPCChain returns a Chain that signs with key and sends through cli.
+func NewRPCChain(ctx context.Context, cli *ethclient.Client, key *ecdsa.PrivateKey) (*RPCChain, error) {
+	chainID, err := cli.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %v", err)
+	}
+	return &RPCChain{cli: cli, key: key, chainID: chainID}, nil
+}
+
+func (c *RPCChain) Submit(ctx context.Context, to common.Address, gasLimit uint64, value *big.Int, data []byte) (*types.Receipt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	from := crypto.PubkeyToAddress(c.key.PublicKey)
+	nonce, err := c.cli.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %v", err)
+	}
+	gasPrice, err := c.cli.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest gas price: %v", err)
+	}
+
+	tx, err := types.SignNewTx(c.key, types.LatestSignerForChainID(c.chainID), &types.LegacyTx{
+		Nonce:    nonce,
+		To:       &to,
+		Value:    value,
+		Gas:      gasLimit,
+		GasPrice: gasPrice,
+		Data:     data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cli.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	return bind.WaitMined(ctx, c.cli, tx)
+}
@@ -1,37 +1,34 @@
 package main
 
 import (
-	"io/ioutil"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 )
 
-func fetchIPFS(cid string) (string, error) {
-	//make a noraml http get request to https://ipfs.io/ipfs/<cid> and print the response
-	resp, err := http.Get("https://ipfs.io/ipfs/" + cid)
+// openIPFS streams the content at cid from the public gateway. The caller
+// must Close the returned body.
+func openIPFS(cid string) (io.ReadCloser, error) {
+	url := "https://ipfs.io/ipfs/" + cid
+	resp, err := http.Get(url)
 	if err != nil {
-		log.Printf("Failed to fetch IPFS content: %v", err)
-		return "", err
+		return nil, err
 	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Failed to read IPFS response: %v", err)
-		return "", err
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &httpStatusError{URL: url, Status: resp.StatusCode}
 	}
-
-	log.Printf("Fetched content from IPFS: %s", string(body))
-	return string(body), nil
-
+	slog.Debug("Opened content from IPFS", "cid", cid, "bytes", resp.ContentLength)
+	return resp.Body, nil
 }
 
 func addIPFS(content string) (string, error) {
 	// Upload the content to IPFS using Pinata
 	pin, err := client.PinJSON(content, nil)
 	if err != nil {
-		log.Printf("Failed to upload to IPFS: %v", err)
 		return "", err
 	}
 
-	log.Printf("Uploaded content to IPFS: %s", pin.IpfsHash)
+	slog.Debug("Uploaded content to IPFS", "cid", pin.IpfsHash)
 	return pin.IpfsHash, nil
 }
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobState is the stage an order has reached in the compute pipeline.
+type JobState string
+
+const (
+	JobReceived     JobState = "received"
+	JobDataFetched  JobState = "data_fetched"
+	JobComputed     JobState = "computed"
+	JobResultPinned JobState = "result_pinned"
+	JobSettled      JobState = "settled"
+	JobFailed       JobState = "failed"
+	JobExpired      JobState = "expired"
+	JobCancelled    JobState = "cancelled"
+)
+
+// jobTransitions lists the state each non-terminal state advances to. Any
+// non-terminal state may also move to JobFailed, JobExpired or JobCancelled.
+var jobTransitions = map[JobState]JobState{
+	JobReceived:     JobDataFetched,
+	JobDataFetched:  JobComputed,
+	JobComputed:     JobResultPinned,
+	JobResultPinned: JobSettled,
+}
+
+// Terminal reports whether no further work will be done for the job.
+func (s JobState) Terminal() bool {
+	return s == JobSettled || s == JobFailed || s == JobExpired || s == JobCancelled
+}
+
+// Job is the persisted progress of a single order. Decrypted data is never
+// stored: a job resumed in JobReceived or JobDataFetched fetches it again.
+type Job struct {
+	DatasetId   *big.Int  `json:"datasetId"`
+	OrderId     *big.Int  `json:"orderId"`
+	TxHash      string    `json:"txHash"`   // transaction that emitted OrderCreated
+	LogIndex    uint      `json:"logIndex"` // index of the OrderCreated log
+	State       JobState  `json:"state"`
+	Result      string    `json:"result,omitempty"`      // aggregate JSON, set in JobComputed
+	ResultCID   string    `json:"resultCid,omitempty"`   // set in JobResultPinned
+	Error       string    `json:"error,omitempty"`       // last failure
+	Attempts    int       `json:"attempts,omitempty"`    // failed attempts so far
+	NextAttempt time.Time `json:"nextAttempt,omitempty"` // earliest time of the next attempt
+	Deadline    time.Time `json:"deadline"`              // last moment completeOrder can succeed
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func jobKey(datasetId, orderId *big.Int) string {
+	return fmt.Sprintf("%s/%s", datasetId, orderId)
+}
+
+// Key identifies the job by (datasetId, orderId).
+func (j Job) Key() string {
+	return jobKey(j.DatasetId, j.OrderId)
+}
+
+func logKey(txHash string, logIndex uint) string {
+	return fmt.Sprintf("%s/%d", txHash, logIndex)
+}
+
+// jobRetention is how long a finished job is kept once its order's deadline
+// has passed. Until then a replayed log is recognised and dropped; after it,
+// a replay would only create a job that expires without doing any work.
+var jobRetention = time.Duration(max(envInt("JOB_RETENTION_HOURS", 24), 0)) * time.Hour
+
+// jobStore is a file-backed record of every order the worker has seen. The
+// whole store is rewritten atomically on each change, which is cheap at the
+// volume of orders a single dataset marketplace produces.
+type jobStore struct {
+	mu   sync.Mutex
+	path string
+	jobs map[string]*Job
+	logs map[string]string // logKey -> jobKey of every log that created a job
+}
+
+func openJobStore(dir string) (*jobStore, error) {
+	s := &jobStore{
+		path: filepath.Join(dir, "jobs.json"),
+		jobs: make(map[string]*Job),
+		logs: make(map[string]string),
+	}
+
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job store: %v", err)
+	}
+	if err := json.Unmarshal(raw, &s.jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse job store %s: %v", s.path, err)
+	}
+	s.prune(time.Now())
+	for key, j := range s.jobs {
+		if j.State != JobCancelled {
+			s.logs[logKey(j.TxHash, j.LogIndex)] = key
+		}
+	}
+	return s, nil
+}
+
+// prune drops finished jobs, and the logs that created them, once they are
+// past jobRetention. It must be called with s.mu held, or before the store
+// is shared.
+func (s *jobStore) prune(now time.Time) {
+	cutoff := now.Add(-jobRetention)
+	for key, j := range s.jobs {
+		if !j.State.Terminal() || !j.Deadline.Before(cutoff) || !j.UpdatedAt.Before(cutoff) {
+			continue
+		}
+		delete(s.jobs, key)
+		if s.logs[logKey(j.TxHash, j.LogIndex)] == key {
+			delete(s.logs, logKey(j.TxHash, j.LogIndex))
+		}
+	}
+}
+
+// SeenLog reports whether the log at (txHash, logIndex) already created a
+// job, so replayed or duplicated logs can be dropped before any work.
+func (s *jobStore) SeenLog(txHash string, logIndex uint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.logs[logKey(txHash, logIndex)]
+	return ok
+}
+
+// ByLog returns the job created by the log at (txHash, logIndex).
+func (s *jobStore) ByLog(txHash string, logIndex uint) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[s.logs[logKey(txHash, logIndex)]]
+	if !ok {
+		return Job{}, false
+	}
+	return *j, true
+}
+
+// Add records a newly announced order in JobReceived. If the order is
+// already known its current job is returned and created is false. A job
+// cancelled by a reorg is replaced, since the order was announced again.
+func (s *jobStore) Add(datasetId, orderId *big.Int, txHash string, logIndex uint, deadline time.Time) (job Job, created bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := jobKey(datasetId, orderId)
+	prev, ok := s.jobs[key]
+	if ok && prev.State != JobCancelled {
+		return *prev, false, nil
+	}
+
+	now := time.Now().UTC()
+	j := &Job{
+		DatasetId: datasetId,
+		OrderId:   orderId,
+		TxHash:    txHash,
+		LogIndex:  logIndex,
+		State:     JobReceived,
+		Deadline:  deadline,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.jobs[key] = j
+	s.logs[logKey(txHash, logIndex)] = key
+	if err := s.save(); err != nil {
+		if prev != nil {
+			s.jobs[key] = prev
+		} else {
+			delete(s.jobs, key)
+		}
+		delete(s.logs, logKey(txHash, logIndex))
+		return Job{}, false, err
+	}
+	return *j, true, nil
+}
+
+// Advance moves job to the next state, persisting any fields set on job
+// alongside it. job is updated in place on success.
+func (s *jobStore) Advance(job *Job, to JobState) error {
+	if next, ok := jobTransitions[job.State]; !ok || next != to {
+		return fmt.Errorf("job %s: invalid transition %s -> %s", job.Key(), job.State, to)
+	}
+	return s.put(job, to)
+}
+
+// Fail moves job to JobFailed, recording reason.
+func (s *jobStore) Fail(job *Job, reason error) error {
+	if job.State.Terminal() {
+		return fmt.Errorf("job %s: invalid transition %s -> %s", job.Key(), job.State, JobFailed)
+	}
+	job.Error = reason.Error()
+	return s.put(job, JobFailed)
+}
+
+// Settle moves job straight to JobSettled from any non-terminal state. It is
+// used when the order turns out to be completed on chain already.
+func (s *jobStore) Settle(job *Job) error {
+	if job.State.Terminal() {
+		return fmt.Errorf("job %s: invalid transition %s -> %s", job.Key(), job.State, JobSettled)
+	}
+	return s.put(job, JobSettled)
+}
+
+// Cancel moves job to JobCancelled because the log that announced it was
+// removed by a reorg. The log is forgotten so that a re-included copy of it
+// creates the job again.
+func (s *jobStore) Cancel(job *Job) error {
+	if job.State.Terminal() {
+		return fmt.Errorf("job %s: invalid transition %s -> %s", job.Key(), job.State, JobCancelled)
+	}
+	job.Error = "OrderCreated log removed by reorg"
+	if err := s.put(job, JobCancelled); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.logs, logKey(job.TxHash, job.LogIndex))
+	return nil
+}
+
+// Expire moves job to JobExpired: it can no longer be settled before the
+// contract's deadline, so no further work is attempted.
+func (s *jobStore) Expire(job *Job) error {
+	if job.State.Terminal() {
+		return fmt.Errorf("job %s: invalid transition %s -> %s", job.Key(), job.State, JobExpired)
+	}
+	job.Error = fmt.Sprintf("cannot settle before deadline %s", job.Deadline.Format(time.RFC3339))
+	return s.put(job, JobExpired)
+}
+
+// Retry records a failed attempt that will be retried at the given time.
+// The job keeps its state so the next attempt resumes where this one stopped.
+func (s *jobStore) Retry(job *Job, reason error, at time.Time) error {
+	if job.State.Terminal() {
+		return fmt.Errorf("job %s: cannot retry in state %s", job.Key(), job.State)
+	}
+	job.Error = reason.Error()
+	job.Attempts++
+	job.NextAttempt = at
+	return s.put(job, job.State)
+}
+
+func (s *jobStore) put(job *Job, to JobState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := job.Key()
+	prev, ok := s.jobs[key]
+	if !ok {
+		return fmt.Errorf("job %s: not found", key)
+	}
+
+	j := *job
+	j.State = to
+	j.UpdatedAt = time.Now().UTC()
+	s.jobs[key] = &j
+	if to.Terminal() {
+		// finishing a job is rare enough to sweep the store on
+		s.prune(j.UpdatedAt)
+	}
+	if err := s.save(); err != nil {
+		s.jobs[key] = prev
+		return err
+	}
+	*job = j
+	return nil
+}
+
+// Pending returns every job that has not reached a terminal state, oldest
+// first.
+func (s *jobStore) Pending() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Job
+	for _, j := range s.jobs {
+		if !j.State.Terminal() {
+			out = append(out, *j)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool {
+		return out[a].CreatedAt.Before(out[b].CreatedAt)
+	})
+	return out
+}
+
+// save must be called with s.mu held.
+func (s *jobStore) save() error {
+	raw, err := json.Marshal(s.jobs)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, raw); err != nil {
+		return fmt.Errorf("failed to save job store: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+)
+
+// Sources of the ECIES keys datasets are encrypted to: keys generated by the
+// worker and sealed to DATA_DIR, or keys derived by rofl-appd from the app
+// identity.
+const (
+	keySourceFile = "file"
+	keySourceAppd = "appd"
+)
+
+const appdKeyID = "healthtrust-ecies"
+
+// keyID names a key by its public half: the first 8 bytes of the Keccak-256
+// hash of the uncompressed public key, in hex. Anyone holding the published
+// public key can compute it, so clients tag envelopes without asking us.
+func keyID(pub *ecdsa.PublicKey) string {
+	return hexutil.Encode(crypto.Keccak256(crypto.FromECDSAPub(pub))[:8])
+}
+
+// keyring holds the current decryption key and every retired one, so
+// datasets encrypted before a rotation stay readable.
+type keyring struct {
+	current string
+	keys    map[string]*ecies.PrivateKey
+	order   []string // key ids, current first, then retired newest first
+}
+
+func newKeyring(current *ecdsa.PrivateKey, retired ...*ecdsa.PrivateKey) *keyring {
+	r := &keyring{keys: make(map[string]*ecies.PrivateKey)}
+	for _, key := range append([]*ecdsa.PrivateKey{current}, retired...) {
+		id := keyID(&key.PublicKey)
+		if _, ok := r.keys[id]; ok {
+			continue
+		}
+		r.keys[id] = ecies.ImportECDSA(key)
+		r.order = append(r.order, id)
+	}
+	r.current = r.order[0]
+	return r
+}
+
+// Current returns the id of the key new datasets should be encrypted to.
+func (r *keyring) Current() string {
+	return r.current
+}
+
+// PublicKey returns the hex public key published for the current key.
+func (r *keyring) PublicKey() string {
+	return hexutil.Encode(crypto.FromECDSAPub(r.keys[r.current].PublicKey.ExportECDSA()))
+}
+
+// Retired reports whether id is a key we hold that is no longer current.
+func (r *keyring) Retired(id string) bool {
+	_, ok := r.keys[id]
+	return ok && id != r.current
+}
+
+// Decrypt opens ciphertext with the key named id. An empty id, used by
+// datasets from before key ids existed, tries every key we hold; ECIES
+// authenticates the message, so the wrong key fails cleanly. It returns the
+// id of the key that worked.
+func (r *keyring) Decrypt(id string, ciphertext []byte) (plaintext []byte, usedID string, err error) {
+	if id != "" {
+		key, ok := r.keys[id]
+		if !ok {
+			return nil, "", fmt.Errorf("encrypted to unknown key %s", id)
+		}
+		plaintext, err := key.Decrypt(ciphertext, nil, nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decrypt with key %s: %v", id, err)
+		}
+		return plaintext, id, nil
+	}
+
+	for _, id := range r.order {
+		if plaintext, err := r.keys[id].Decrypt(ciphertext, nil, nil); err == nil {
+			return plaintext, id, nil
+		}
+	}
+	return nil, "", fmt.Errorf("no key in the keyring (%d) decrypts the data", len(r.order))
+}
+
+// loadKeyring loads the current and retired keys from KEY_SOURCE. Setting
+// ROTATE_KEY to a new label retires the current key once: in file mode a
+// fresh key is generated, with appd the label selects a different derived
+// key. An empty rotation keeps whatever key is current.
+func loadKeyring(ctx context.Context, rotation string) (*keyring, error) {
+	switch source := envOr("KEY_SOURCE", keySourceFile); source {
+	case keySourceFile:
+		return loadSealedKeyring(filepath.Join(DATA_DIR, "ecies.key"), rotation)
+	case keySourceAppd:
+		return loadAppdKeyring(ctx, filepath.Join(DATA_DIR, "appd-keys.json"), rotation)
+	default:
+		return nil, fmt.Errorf("unknown KEY_SOURCE %q", source)
+	}
+}
+
+// openKeyring loads the keys from KEY_SOURCE without changing them, for
+// commands that only inspect the keyring. Unlike loadKeyring it never
+// generates a key or records a label, and fails if the worker has not
+// created its keys in DATA_DIR yet.
+func openKeyring(ctx context.Context) (*keyring, error) {
+	switch source := envOr("KEY_SOURCE", keySourceFile); source {
+	case keySourceFile:
+		return openSealedKeyring(filepath.Join(DATA_DIR, "ecies.key"))
+	case keySourceAppd:
+		return openAppdKeyring(ctx, filepath.Join(DATA_DIR, "appd-keys.json"))
+	default:
+		return nil, fmt.Errorf("unknown KEY_SOURCE %q", source)
+	}
+}
+
+// encryptionKeyFile is a sealed key as stored in DATA_DIR. Rotation is the
+// ROTATE_KEY label the key was generated for, so a restart with the same
+// label keeps the key instead of rotating again.
+type encryptionKeyFile struct {
+	Key       string    `json:"key"` // hex
+	Rotation  string    `json:"rotation,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// loadSealedKeyring reads the current key at path and the retired keys
+// next to it, named path.<unix time of creation>. DATA_DIR lives on the
+// ROFL persistent volume, which is encrypted to the enclave, so the keys
+// never leave it in the clear.
+func loadSealedKeyring(path, rotation string) (*keyring, error) {
+	current, err := loadSealedKey(path, rotation)
+	if err != nil {
+		return nil, err
+	}
+	return sealedKeyring(path, current)
+}
+
+// openSealedKeyring is loadSealedKeyring for a key that must already exist.
+func openSealedKeyring(path string) (*keyring, error) {
+	f, err := readSealedKey(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no encryption key at %s; start the worker once to generate it", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	current, err := crypto.HexToECDSA(strings.TrimPrefix(f.Key, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key in %s: %v", path, err)
+	}
+	return sealedKeyring(path, current)
+}
+
+// sealedKeyring adds the retired keys stored next to path to current.
+func sealedKeyring(path string, current *ecdsa.PrivateKey) (*keyring, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	// newest first; the suffixes are unix times of equal width
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	var retired []*ecdsa.PrivateKey
+	for _, m := range matches {
+		if strings.HasSuffix(m, ".tmp") {
+			continue
+		}
+		f, err := readSealedKey(m)
+		if err != nil {
+			return nil, err
+		}
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(f.Key, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key in %s: %v", m, err)
+		}
+		retired = append(retired, key)
+	}
+	return newKeyring(current, retired...), nil
+}
+
+// loadSealedKey reads the key at path, generating it on first boot or when
+// rotation differs from the label it was created for. The key it replaces
+// is kept as a retired key.
+func loadSealedKey(path, rotation string) (*ecdsa.PrivateKey, error) {
+	f, err := readSealedKey(path)
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Info("No encryption key found, generating one", "path", path)
+		return createSealedKey(path, rotation)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(f.Key, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key in %s: %v", path, err)
+	}
+	if rotation == "" || rotation == f.Rotation {
+		return key, nil
+	}
+
+	retired := fmt.Sprintf("%s.%d", path, f.CreatedAt.Unix())
+	if err := os.Rename(path, retired); err != nil {
+		return nil, fmt.Errorf("failed to retire encryption key: %v", err)
+	}
+	slog.Info("Rotating encryption key", "rotation", rotation, "retiredKeyId", keyID(&key.PublicKey), "retiredPath", retired)
+	return createSealedKey(path, rotation)
+}
+
+func readSealedKey(path string) (encryptionKeyFile, error) {
+	var f encryptionKeyFile
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, err
+	}
+	if err != nil {
+		return f, fmt.Errorf("failed to read encryption key: %v", err)
+	}
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return f, fmt.Errorf("failed to parse encryption key %s: %v", path, err)
+	}
+	return f, nil
+}
+
+func createSealedKey(path, rotation string) (*ecdsa.PrivateKey, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate encryption key: %v", err)
+	}
+	raw, err := json.Marshal(encryptionKeyFile{
+		Key:       hexutil.Encode(crypto.FromECDSA(key)),
+		Rotation:  rotation,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, raw); err != nil {
+		return nil, fmt.Errorf("failed to seal encryption key: %v", err)
+	}
+	return key, nil
+}
+
+// appdKeyLabels records the rotation labels used so far, oldest first. The
+// keys themselves are derived again on every boot.
+type appdKeyLabels struct {
+	Labels []string `json:"labels"`
+}
+
+// loadAppdKeyring derives the current key and every key used before it from
+// rofl-appd. The list of labels is kept at path; the key for label "" is the
+// one in use before the first rotation.
+func loadAppdKeyring(ctx context.Context, path, rotation string) (*keyring, error) {
+	history, err := readAppdKeyLabels(path)
+	if errors.Is(err, os.ErrNotExist) {
+		history.Labels = []string{""}
+	} else if err != nil {
+		return nil, err
+	}
+	if rotation != "" && !slices.Contains(history.Labels, rotation) {
+		slog.Info("Rotating encryption key", "rotation", rotation)
+		history.Labels = append(history.Labels, rotation)
+	}
+	if rotation == "" {
+		rotation = history.Labels[len(history.Labels)-1]
+	}
+	raw, err := json.Marshal(history)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, raw); err != nil {
+		return nil, fmt.Errorf("failed to save key labels: %v", err)
+	}
+	return deriveAppdKeyring(ctx, history, rotation)
+}
+
+// openAppdKeyring is loadAppdKeyring for labels that must already exist,
+// with the last one current.
+func openAppdKeyring(ctx context.Context, path string) (*keyring, error) {
+	history, err := readAppdKeyLabels(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no key labels at %s; start the worker once to record them", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deriveAppdKeyring(ctx, history, history.Labels[len(history.Labels)-1])
+}
+
+func readAppdKeyLabels(path string) (appdKeyLabels, error) {
+	var history appdKeyLabels
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return history, err
+	}
+	if err != nil {
+		return history, fmt.Errorf("failed to read key labels: %v", err)
+	}
+	if err := json.Unmarshal(raw, &history); err != nil {
+		return history, fmt.Errorf("failed to parse key labels %s: %v", path, err)
+	}
+	if len(history.Labels) == 0 {
+		return history, fmt.Errorf("no key labels in %s", path)
+	}
+	return history, nil
+}
+
+// deriveAppdKeyring derives the key for rotation from rofl-appd, and the
+// keys for every other label in history as retired keys.
+func deriveAppdKeyring(ctx context.Context, history appdKeyLabels, rotation string) (*keyring, error) {
+	socket := envOr("APPD_SOCKET", "/run/rofl-appd.sock")
+	derive := func(label string) (*ecdsa.PrivateKey, error) {
+		id := appdKeyID
+		if label != "" {
+			id += "-" + label
+		}
+		return appdGenerateKey(ctx, socket, id)
+	}
+	current, err := derive(rotation)
+	if err != nil {
+		return nil, err
+	}
+	var retired []*ecdsa.PrivateKey
+	for _, label := range slices.Backward(history.Labels) {
+		if label == rotation {
+			continue
+		}
+		key, err := derive(label)
+		if err != nil {
+			return nil, err
+		}
+		retired = append(retired, key)
+	}
+	return newKeyring(current, retired...), nil
+}
+
+// publishPubKey makes sure the contract advertises pubKey. It publishes on
+// first boot, when the contract has no key yet, and when rotating. Any other
+// mismatch means the contract points at a key we do not hold, and
+// overwriting it would strand every dataset encrypted to it, so it is an
+// error rather than something to fix silently.
+func publishPubKey(ctx context.Context, pubKey string, rotating bool) error {
+	ht, cli, err := dialContract()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	current, err := ht.GetPubKey(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return fmt.Errorf("failed to read public key from contract: %v", err)
+	}
+	switch {
+	case strings.EqualFold(current, pubKey):
+		slog.Info("Contract already has our public key", "pubKey", pubKey)
+		return nil
+	case current != "" && !rotating:
+		return fmt.Errorf("contract public key %s does not match our key %s; set ROTATE_KEY to publish ours", current, pubKey)
+	}
+	return storePubKeyInSC(pubKey)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/LeonardoRyuta/HealthTrust/internal/fakeappd"
+)
+
+// useDataDir points DATA_DIR at a fresh directory for the test.
+func useDataDir(t *testing.T) string {
+	t.Helper()
+	prev := DATA_DIR
+	DATA_DIR = t.TempDir()
+	t.Cleanup(func() { DATA_DIR = prev })
+	return DATA_DIR
+}
+
+// dirContents lists the files in dir with their contents.
+func dirContents(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, e := range entries {
+		raw, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[e.Name()] = string(raw)
+	}
+	return files
+}
+
+func TestOpenKeyringSealed(t *testing.T) {
+	t.Setenv("KEY_SOURCE", keySourceFile)
+	dir := useDataDir(t)
+	ctx := context.Background()
+
+	if _, err := openKeyring(ctx); err == nil {
+		t.Fatal("opened a keyring in an empty DATA_DIR")
+	}
+	if files := dirContents(t, dir); len(files) != 0 {
+		t.Fatalf("openKeyring wrote %v", slices.Collect(maps.Keys(files)))
+	}
+
+	if _, err := loadKeyring(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadKeyring(ctx, "rotation-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := dirContents(t, dir)
+	opened, err := openKeyring(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(opened.order, loaded.order) {
+		t.Errorf("opened keys %v, want %v", opened.order, loaded.order)
+	}
+	if after := dirContents(t, dir); !maps.Equal(before, after) {
+		t.Error("openKeyring changed DATA_DIR")
+	}
+}
+
+func TestOpenKeyringAppd(t *testing.T) {
+	t.Setenv("KEY_SOURCE", keySourceAppd)
+	t.Setenv("APPD_SOCKET", serveAppd(t, fakeappd.NewHandler(nil)))
+	dir := useDataDir(t)
+	ctx := context.Background()
+
+	if _, err := openKeyring(ctx); err == nil {
+		t.Fatal("opened a keyring without key labels")
+	}
+	if files := dirContents(t, dir); len(files) != 0 {
+		t.Fatalf("openKeyring wrote %v", slices.Collect(maps.Keys(files)))
+	}
+
+	if _, err := loadKeyring(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadKeyring(ctx, "rotation-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := dirContents(t, dir)
+	opened, err := openKeyring(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(opened.order, loaded.order) {
+		t.Errorf("opened keys %v, want %v", opened.order, loaded.order)
+	}
+	if after := dirContents(t, dir); !maps.Equal(before, after) {
+		t.Error("openKeyring changed DATA_DIR")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are never logged, whatever
+// their type. Matching ignores case.
+var sensitiveKeys = map[string]bool{
+	"privatekey": true,
+	"secret":     true,
+	"password":   true,
+	"passphrase": true,
+	"plaintext":  true,
+	"payload":    true,
+	"content":    true,
+	"entries":    true,
+}
+
+// newLogger returns the worker's logger. Attributes holding key material or
+// health records are replaced by redactAttr before any handler sees them,
+// so they cannot be emitted however they are logged. format is "json" or
+// "text".
+func newLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+	if format == "text" {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// setupLogging installs the logger configured by LOG_FORMAT and LOG_LEVEL
+// as the default for slog and for the standard log package.
+func setupLogging() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(envOr("LOG_LEVEL", "info"))); err != nil {
+		level = slog.LevelInfo
+	}
+	slog.SetDefault(newLogger(os.Stderr, envOr("LOG_FORMAT", "json"), level))
+}
+
+// redactAttr drops the value of attributes that are sensitive by name or by
+// type.
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+	switch a.Value.Any().(type) {
+	case *ecdsa.PrivateKey, ecdsa.PrivateKey, *ecies.PrivateKey, ecies.PrivateKey,
+		DataEntry, []DataEntry, Data, *Data, metricValue:
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
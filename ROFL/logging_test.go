@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+)
+
+// captureLogs routes slog and the log package to a buffer for the rest of
+// the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev, prevOut, prevFlags := slog.Default(), log.Writer(), log.Flags()
+	slog.SetDefault(newLogger(&buf, "json", slog.LevelDebug))
+	t.Cleanup(func() {
+		slog.SetDefault(prev)
+		log.SetOutput(prevOut)
+		log.SetFlags(prevFlags)
+	})
+	return &buf
+}
+
+func assertNoSecrets(t *testing.T, logs string, secrets ...string) {
+	t.Helper()
+	for _, s := range secrets {
+		if strings.Contains(logs, s) {
+			t.Errorf("log output contains secret %q:\n%s", s, logs)
+		}
+	}
+}
+
+func TestRedactAttr(t *testing.T) {
+	buf := captureLogs(t)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyHex := hexutil.Encode(crypto.FromECDSA(key))
+	entries := []DataEntry{heartRateEntry(1700000000, 187, 91.2345)}
+
+	slog.Info("by key", "privateKey", keyHex, "Plaintext", "heartRate: 187", "passphrase", "hunter2")
+	slog.Info("by type", "k", key, "ecies", ecies.ImportECDSA(key), "e", entries, "one", entries[0])
+	slog.Info("in group", slog.Group("dataset", "content", "bloodOxygenLevel: 91.2345"))
+
+	logs := buf.String()
+	assertNoSecrets(t, logs, strings.TrimPrefix(keyHex, "0x"), key.D.String(), "heartRate", "1700000000", "91.2345", "hunter2")
+	if n := strings.Count(logs, redacted); n != 8 {
+		t.Errorf("got %d redactions, want 8:\n%s", n, logs)
+	}
+}
+
+// TestKeyLifecycleLogsNoSecrets runs key generation, rotation and
+// decryption, which all log, and checks that neither key material nor the
+// decrypted records reach the output.
+func TestKeyLifecycleLogsNoSecrets(t *testing.T) {
+	buf := captureLogs(t)
+	path := filepath.Join(t.TempDir(), "ecies.key")
+
+	first, err := loadSealedKeyring(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey := first.keys[first.Current()]
+	plaintext := `[{"timestamp":1700000000,"heartRate":187,"bloodOxygenLevel":91.2345}]`
+	ciphertext, err := ecies.Encrypt(rand.Reader, &oldKey.PublicKey, []byte(plaintext), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prevKeys := keys
+	t.Cleanup(func() { keys = prevKeys })
+	keys, err = loadSealedKeyring(path, "rotation-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := json.Marshal(keyEnvelope{KeyID: first.Current(), Ciphertext: ciphertext})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecryptData(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != plaintext {
+		t.Fatalf("DecryptData = %q, want %q", got, plaintext)
+	}
+
+	secrets := []string{plaintext, "heartRate", "1700000000", "91.2345"}
+	for _, k := range keys.keys {
+		secrets = append(secrets, hexutil.Encode(crypto.FromECDSA(k.ExportECDSA()))[2:])
+	}
+	logs := buf.String()
+	if !strings.Contains(logs, "Rotating encryption key") {
+		t.Fatalf("expected rotation to be logged:\n%s", logs)
+	}
+	assertNoSecrets(t, logs, secrets...)
+}
+
+// TestMetricParseErrorsCarryNoValues checks that rejecting a payload value
+// does not copy it into the error, which ends up in the logs and jobs.json.
+func TestMetricParseErrorsCarryNoValues(t *testing.T) {
+	for _, tc := range []struct {
+		metric, raw, secret string
+	}{
+		{"heartRate", `187.25`, "187.25"},
+		{"heartRate", `"187"`, "187"},
+		{"heartRate", `1e999`, "1e999"},
+		{"sleepStage", `"hypnagogic"`, "hypnagogic"},
+		{"sleepStage", `7777`, "7777"},
+		{"bloodPressure", `{"systolic":121}`, "121"},
+		{"bloodPressure", `{"systolic":121,"diastolic":"83"}`, "83"},
+	} {
+		_, err := metricsByName[tc.metric].parse(json.RawMessage(tc.raw))
+		if err == nil {
+			t.Errorf("%s %s: no error", tc.metric, tc.raw)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tc.metric+": ") {
+			t.Errorf("%s %s: error %q does not name the metric", tc.metric, tc.raw, err)
+		}
+		assertNoSecrets(t, err.Error(), tc.secret)
+	}
+}
+
+// TestPayloadErrorsCarryNoValues feeds each payload format a bad value and
+// checks that the error locates it without quoting it.
+func TestPayloadErrorsCarryNoValues(t *testing.T) {
+	for _, tc := range []struct {
+		contentType, payload, want, secret string
+	}{
+		{"text/csv", "timestamp,heartRate\n1700000000,72\n17000x0060,73\n", "line 3: column 1: timestamp", "17000x0060"},
+		{`text/csv; timestamp=rfc3339`, "timestamp,heartRate\n2023-11-14T22:13:20Z-ish,72\n", "line 2: column 1: timestamp", "2023-11-14T22:13:20Z-ish"},
+		{"text/csv", "timestamp,heartRate\n1700000000,187.25\n", "line 2: column 2: heartRate", "187.25"},
+		{"text/csv", "timestamp,sleepStage\n1700000000,hypnagogic\n", "line 2: column 2: sleepStage", "hypnagogic"},
+		{"application/x-ndjson", `{"timestamp":1700000000,"heartRate":72}` + "\n" + `{"timestamp":1700000060,"heartRate":187 91.2345}`, "line 2: column", "91.2345"},
+		{"application/x-ndjson", `[{"timestamp":1700000000,"heartRate":187}]`, "line 1: want a JSON object", "187"},
+		{"application/x-ndjson", `{"timestamp":"yesterday 1700000000","heartRate":72}`, "line 1: timestamp", "1700000000"},
+		{"application/x-ndjson", `{"timestamp":1700000000,"bloodOxygenLevel":"91.2345"}`, "line 1: bloodOxygenLevel", "91.2345"},
+		{"", `{"schemaVersion":2,"entries":1700000000}`, "expected [", "1700000000"},
+		{"", `{"schemaVersion":1,"entries":[{"timestamp":1700000000,"heartRate":72.5,"bloodOxygenLevel":97}]}`, "entry 0: heartRate: want int64, got a JSON number", "72.5"},
+		{"", `{"schemaVersion":2,"entries":[{"timestamp":1700000000.5,"metrics":{"heartRate":72}}]}`, "entry 0: timestamp: want int64", "1700000000"},
+		{"", `[{"timestamp":1700000000,"heartRate":187.25,"bloodOxygenLevel":97}]`, "entry 0: heartRate: want int64", "187.25"},
+		{"", `"[{timestamp: 1700000000, heartRate: 72, bloodOxygenLevel: "97.5"}]"`, "entry 0: bloodOxygenLevel: want float64, got a JSON string", "97.5"},
+	} {
+		_, err := decodePayload(tc.contentType, strings.NewReader(tc.payload), func(DataEntry) {})
+		if err == nil {
+			t.Errorf("%s %q: no error", tc.contentType, tc.payload)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s %q: error %q does not contain %q", tc.contentType, tc.payload, err, tc.want)
+		}
+		assertNoSecrets(t, err.Error(), tc.secret)
+	}
+}
@@ -3,235 +3,378 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
 	// "io"
-	"log"
+	"log/slog"
 	"math/big"
-	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/zde37/pinata-go-sdk/pinata"
 )
 
 // ---- ENV ----
 var (
-	RPC_URL       = "https://testnet.sapphire.oasis.io"
+	RPC_URL       = envOr("RPC_URL", "https://testnet.sapphire.oasis.io")
+	WS_URL        = envOr("WS_URL", "wss://testnet.sapphire.oasis.io/ws")
+	DATA_DIR      = envOr("DATA_DIR", "/data")
 	CONTRACT_ADDR = common.HexToAddress("0x50739936402555eE6034c09FA77e007036fD23A1")
 	auth          *pinata.Auth
 	client        *pinata.Client
-	privKey       *string
-	pubKey        *string
+	keys          *keyring
+	jobs          *jobStore
+	pool          *workerPool
+	txs           Submitter
 )
 
 func main() {
-	cli, _ := ethclient.Dial("wss://testnet.sapphire.oasis.io/ws")
+	setupLogging()
 
-	sig := []byte("OrderCreated(uint256,uint256,address,uint256)")
-	topic := crypto.Keccak256Hash(sig)
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), os.Args[1:]); err != nil {
+			fatal("Command failed", "err", err)
+		}
+		return
+	}
+
+	topic, err := orderCreatedTopic()
+	if err != nil {
+		fatal("Error reading OrderCreated topic", "err", err)
+	}
 
-	log.Printf("Topic: %s", topic.Hex())
+	slog.Info("Watching OrderCreated", "topic", topic.Hex(), "contract", CONTRACT_ADDR.Hex())
 
 	q := ethereum.FilterQuery{
 		Addresses: []common.Address{CONTRACT_ADDR},
 		Topics:    [][]common.Hash{{topic}},
 	}
 
-	logs := make(chan types.Log)
-	sub, err := cli.SubscribeFilterLogs(context.Background(), q, logs)
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	auth = pinata.NewAuthWithJWT(os.Getenv("JWT_TOKEN"))
 	client = pinata.New(auth)
 
-	pk, pu, err := GenerateKeyPair()
+	rotation := os.Getenv("ROTATE_KEY")
+	keys, err = loadKeyring(context.Background(), rotation)
 	if err != nil {
-		log.Printf("Error generating key pair: %v", err)
-		return
+		fatal("Error loading encryption keys", "err", err)
+	}
+	slog.Info("Loaded encryption keys", "keyId", keys.Current(), "retired", len(keys.order)-1)
+
+	txs, err = newSubmitter(context.Background())
+	if err != nil {
+		fatal("Error setting up transactions", "err", err)
 	}
 
-	privKey = &pk
-	pubKey = &pu
+	if err := publishPubKey(context.Background(), keys.PublicKey(), rotation != ""); err != nil {
+		fatal("Error publishing public key", "err", err)
+	}
 
-	err = storePubKeyInSC(pu)
+	jobs, err = openJobStore(DATA_DIR)
 	if err != nil {
-		log.Printf("Error storing public key in SC: %v", err)
-		return
+		fatal("Error opening job store", "err", err)
 	}
+	ctx := context.Background()
+	pool = newWorkerPool(envInt("WORKERS", 2), envInt("QUEUE_SIZE", 32))
+	pool.Start(ctx, computeHandler)
 
-	log.Println("Listening for events...")
+	for _, job := range jobs.Pending() {
+		slog.Info("Resuming order", "orderId", job.OrderId, "datasetId", job.DatasetId, "state", job.State)
+		scheduleJob(ctx, job)
+	}
 
-	for {
-		select {
-		case err := <-sub.Err():
-			log.Println("Error:", err)
-		case vLog := <-logs:
-			go handle(vLog, topic)
-		}
+	// handle records the order before returning, so the checkpoint only moves
+	// past logs whose orders are safely in the job store.
+	cfg := watcherConfig{
+		Mode:          envOr("INGEST_MODE", ingestWebsocket),
+		Confirmations: uint64(max(envInt("CONFIRMATIONS", 0), 0)),
+		PollInterval:  time.Duration(max(envInt("POLL_INTERVAL_SECONDS", 6), 1)) * time.Second,
+		// Sapphire rejects eth_getLogs over more than 100 blocks.
+		LogRange: uint64(max(envInt("POLL_RANGE", 100), 1)),
+	}
+	watcher, err := newOrderWatcher(q, newCheckpoint(DATA_DIR), cfg, func(vLog types.Log) {
+		handle(vLog, topic)
+	})
+	if err != nil {
+		fatal("Error starting log ingestion", "err", err)
+	}
+	if err := watcher.run(ctx); err != nil {
+		fatal("Log ingestion stopped", "err", err)
 	}
 }
 
 func handle(vLog types.Log, topic common.Hash) {
-	log.Printf("Received log: %v", vLog)
+	slog.Debug("Received log", "tx", vLog.TxHash.Hex(), "index", vLog.Index, "block", vLog.BlockNumber, "removed", vLog.Removed)
 
-	var ev struct {
-		DatasetId  *big.Int
-		OrderId    *big.Int
-		Researcher common.Address
-		Amount     *big.Int
+	if vLog.Removed {
+		handleRemoved(vLog)
+		return
 	}
 
-	abiObj, _ := abi.JSON(strings.NewReader(`[{
-	  "anonymous":false,
-	  "inputs":[
-		 {"indexed":true,"name":"datasetId","type":"uint256"},
-		 {"indexed":true,"name":"orderId","type":"uint256"},
-		 {"indexed":true,"name":"researcher","type":"address"},
-		 {"indexed":false,"name":"amount","type":"uint256"}],
-	  "name":"OrderCreated",
-	  "type":"event"}]`))
-
-	if vLog.Topics[0] == topic {
-		// indexed fields come from topics[1..3]
-		ev.DatasetId = new(big.Int).SetBytes(vLog.Topics[1].Bytes())
-		ev.OrderId = new(big.Int).SetBytes(vLog.Topics[2].Bytes())
-		ev.Researcher = common.BytesToAddress(vLog.Topics[3].Bytes())
+	if len(vLog.Topics) == 0 || vLog.Topics[0] != topic {
+		return
+	}
+	ev, err := parseOrderCreated(vLog)
+	if err != nil {
+		slog.Error("Error parsing OrderCreated log", "tx", vLog.TxHash.Hex(), "index", vLog.Index, "err", err)
+		return
+	}
+	orderId := ev.OrderId
+	datasetId := ev.DatasetId
 
-		// non‑indexed amount sits in Data
-		if err := abiObj.UnpackIntoInterface(&ev, "OrderCreated", vLog.Data); err != nil {
-			log.Fatal(err)
-		}
-		fmt.Printf("order %d on dataset %d by %s amount %s\n",
-			ev.OrderId, ev.DatasetId, ev.Researcher.Hex(), ev.Amount)
+	if jobs.SeenLog(vLog.TxHash.Hex(), vLog.Index) {
+		slog.Info("Ignoring duplicate log", "tx", vLog.TxHash.Hex(), "index", vLog.Index, "orderId", orderId)
+		return
+	}
+	slog.Info("Order created", "orderId", orderId, "datasetId", datasetId,
+		"researcher", ev.Researcher.Hex(), "amountBaseUnits", ev.Amount) // formatted once runJob reads the token
 
-		orderId := ev.OrderId.Uint64()
-		datasetId := ev.DatasetId.Uint64()
+	deadline, err := blockDeadline(context.Background(), vLog.BlockNumber)
+	if err != nil {
+		// runJob corrects the deadline from the order itself
+		slog.Warn("Error getting order deadline", "orderId", orderId, "err", err)
+		deadline = time.Now().UTC().Add(orderTTL)
+	}
 
-		computeHandler(orderId, datasetId)
+	job, created, err := jobs.Add(datasetId, orderId, vLog.TxHash.Hex(), vLog.Index, deadline)
+	if err != nil {
+		fatal("Error recording order", "orderId", orderId, "datasetId", datasetId, "err", err)
+	}
+	if !created {
+		slog.Info("Order already known", "orderId", orderId, "datasetId", datasetId, "state", job.State)
+		return
+	}
+	if err := pool.Submit(context.Background(), job); err != nil {
+		slog.Error("Error queueing order", "orderId", orderId, "datasetId", datasetId, "err", err)
 	}
 }
 
-func computeHandler(orderId uint64, datasetId uint64) {
-	log.Printf("Order ID: %d", orderId)
-
-	order, err := getStake(orderId, datasetId)
-	if err != nil {
-		log.Printf("Error getting order: %v", err)
+// handleRemoved cancels the job created by a log that a reorg took out of the
+// chain, unless a worker has already picked it up. A started job is left to
+// fail on its own: getStake will not find the order.
+func handleRemoved(vLog types.Log) {
+	job, ok := jobs.ByLog(vLog.TxHash.Hex(), vLog.Index)
+	if !ok || job.State.Terminal() {
 		return
 	}
-	log.Printf("Order: %v", order)
-	log.Printf("Order Dataset ID: %d", order.DatasetId)
+	if !pool.Cancel(job.Key()) {
+		slog.Warn("Order removed by a reorg after work started", "orderId", job.OrderId, "datasetId", job.DatasetId)
+		return
+	}
+	slog.Info("Cancelling order removed by a reorg", "orderId", job.OrderId, "datasetId", job.DatasetId)
+	if err := jobs.Cancel(&job); err != nil {
+		slog.Error("Error recording cancellation", "orderId", job.OrderId, "datasetId", job.DatasetId, "err", err)
+	}
+}
 
-	datares, err := getDataHash(order.DatasetId)
-	if err != nil {
-		log.Printf("Error getting data hash: %v", err)
+// computeHandler drives job through the pipeline from whatever state it was
+// left in, recording each step in the job store.
+func computeHandler(job Job) {
+	if !job.Deadline.IsZero() && !settleable(job.Deadline) {
+		expireJob(&job)
 		return
 	}
-	log.Printf("Data: %v", datares)
 
-	// text, err := fetchIPFS(data.IPFSHash)
-	encryptedText, err := fetchIPFS(datares.IPFSHash) // Test IPFS hash
-	if err != nil {
-		log.Printf("Error fetching IPFS content: %v", err)
+	if err := runJob(&job); err != nil {
+		if errors.Is(err, errOrderExpiring) {
+			expireJob(&job)
+			return
+		}
+		if isTransient(err) && job.Attempts+1 < maxAttempts {
+			delay := retryDelay(job.Attempts + 1)
+			if !settleable(job.Deadline.Add(-delay)) {
+				slog.Warn("Order failed and no retry fits before its deadline", "orderId", job.OrderId, "datasetId", job.DatasetId, "err", err)
+				expireJob(&job)
+				return
+			}
+			slog.Warn("Order failed, retrying", "orderId", job.OrderId, "datasetId", job.DatasetId, "state", job.State, "in", delay.Round(time.Second), "err", err)
+			if err := jobs.Retry(&job, err, time.Now().Add(delay)); err != nil {
+				slog.Error("Error recording retry", "orderId", job.OrderId, "datasetId", job.DatasetId, "err", err)
+				return
+			}
+			scheduleJob(context.Background(), job)
+			return
+		}
+
+		slog.Error("Order failed", "orderId", job.OrderId, "datasetId", job.DatasetId, "state", job.State, "attempts", job.Attempts+1, "err", err)
+		if err := jobs.Fail(&job, err); err != nil {
+			slog.Error("Error recording failure", "orderId", job.OrderId, "datasetId", job.DatasetId, "err", err)
+		}
 		return
 	}
+	slog.Info("Order settled", "orderId", job.OrderId, "datasetId", job.DatasetId)
+}
 
-	text, err := DecryptData([]byte(encryptedText))
+// expireJob records that job can no longer be settled in time.
+func expireJob(job *Job) {
+	slog.Warn("Skipping order too close to its deadline", "orderId", job.OrderId, "datasetId", job.DatasetId, "deadline", job.Deadline)
+	if err := jobs.Expire(job); err != nil {
+		slog.Error("Error recording expiry", "orderId", job.OrderId, "datasetId", job.DatasetId, "err", err)
+	}
+}
+
+// errOrderExpiring stops a job whose order can no longer be settled before
+// the contract's deadline.
+var errOrderExpiring = errors.New("order too close to its deadline")
+
+func runJob(job *Job) error {
+	order, err := getStake(job.OrderId, job.DatasetId)
 	if err != nil {
-		log.Printf("Error decrypting IPFS content: %v", err)
-		return
+		return classifyErr("get order", err)
 	}
+	meta := getTokenMeta(context.Background(), common.HexToAddress(order.TokenAddress))
+	slog.Info("Processing order", "orderId", order.OrderId, "datasetId", order.DatasetId, "state", job.State,
+		"amount", meta.String(order.Amount), "patient", order.Patient)
 
-	log.Printf("IPFS content: %s", text)
-	// Remove outer quotes if needed
-	fixedText := strings.ReplaceAll(text, "\"[", "[")
-	fixedText = strings.ReplaceAll(fixedText, "]\"", "]")
+	if order.Completed {
+		slog.Info("Order already completed on chain", "orderId", job.OrderId, "datasetId", job.DatasetId)
+		return jobs.Settle(job)
+	}
 
-	// Fix missing quotes around timestamp and ensure it's consistent
-	fixedText = strings.ReplaceAll(fixedText, "{timestamp:", "{\"timestamp\":")
-	fixedText = strings.ReplaceAll(fixedText, "{ timestamp:", "{\"timestamp\":")
+	job.Deadline = orderDeadline(order.Timestamp)
+	if !settleable(job.Deadline) {
+		return errOrderExpiring
+	}
 
-	// Fix other fields
-	fixedText = strings.ReplaceAll(fixedText, ", heartRate:", ", \"heartRate\":")
-	fixedText = strings.ReplaceAll(fixedText, ", bloodOxygenLevel:", ", \"bloodOxygenLevel\":")
+	if job.State == JobReceived || job.State == JobDataFetched {
+		declared, err := getDatasetMetrics(order.DatasetId)
+		if err != nil {
+			return classifyErr("get dataset metrics", err)
+		}
+		if len(declared) == 0 {
+			// Nothing declared: aggregate what the payload carries, as the
+			// worker did before datasets declared their metrics.
+			slog.Info("Dataset declares no metrics, using the ones it carries", "orderId", job.OrderId, "datasetId", job.DatasetId)
+		}
+		// Entries are validated and aggregated as they are decrypted and
+		// parsed; the dataset is never held in memory.
+		validator := newEntryValidator(declared)
+		data, err := fetchDataset(order.DatasetId, validator.add)
+		if err != nil {
+			return err
+		}
+		if job.State == JobReceived {
+			if err := jobs.Advance(job, JobDataFetched); err != nil {
+				return err
+			}
+		}
 
-	// Fix missing commas between objects
-	fixedText = strings.ReplaceAll(fixedText, "},{", "},{")
+		report := validator.finish(data.Rejected)
+		slog.Info("Validated dataset", "orderId", job.OrderId, "datasetId", job.DatasetId,
+			"total", report.Total, "accepted", report.Accepted, "rejected", report.Rejected, "outOfOrder", report.OutOfOrder)
+		result, err := computeResult(validator.summaries(), report)
+		if err != nil {
+			return err
+		}
+		job.Result = result
+		if err := jobs.Advance(job, JobComputed); err != nil {
+			return err
+		}
+	}
 
-	// Use regex to fix any remaining issues - better approach would be to add this
-	fixedText = strings.ReplaceAll(fixedText, "}{", " },{")
+	if job.State == JobComputed {
+		averageDataCID, err := addIPFS(job.Result)
+		if err != nil {
+			return classifyErr("pin result", err)
+		}
+		slog.Info("Pinned result", "orderId", job.OrderId, "datasetId", job.DatasetId, "cid", averageDataCID)
 
-	log.Printf("Fixed text: %s", fixedText)
-	var dataEntries []DataEntry
-	if err := json.Unmarshal([]byte(fixedText), &dataEntries); err != nil {
-		log.Printf("Error unmarshalling JSON: %v", err)
-		return
+		job.ResultCID = averageDataCID
+		if err := jobs.Advance(job, JobResultPinned); err != nil {
+			return err
+		}
 	}
 
-	// --- 3. process data ----------------------------------------------
-	var averageHR int64
-	var averageBOL float64
-	for i := range dataEntries {
-		entry := &dataEntries[i]
-		averageHR += entry.HeartRate
-		averageBOL += entry.BloodOxygenLevel
+	if job.State == JobResultPinned {
+		// The order may have been settled while we computed, e.g. by a
+		// previous run whose receipt we never saw.
+		order, err := getStake(job.OrderId, job.DatasetId)
+		if err != nil {
+			return classifyErr("get order", err)
+		}
+		if order.Completed {
+			slog.Info("Order completed on chain during compute", "orderId", job.OrderId, "datasetId", job.DatasetId)
+			return jobs.Settle(job)
+		}
+
+		err = completeOrder(order.OrderId, order.DatasetId, job.ResultCID)
+		if err != nil {
+			return classifyErr("complete order", err)
+		}
+		if err := jobs.Advance(job, JobSettled); err != nil {
+			return err
+		}
 	}
-	averageHR /= int64(len(dataEntries))
-	averageBOL /= float64(len(dataEntries))
+	return nil
+}
 
-	//add to ipfs an object with the average heart rate and blood oxygen level
-	averageData := map[string]interface{}{
-		"averageHeartRate":        averageHR,
-		"averageBloodOxygenLevel": averageBOL,
+// fetchDataset downloads, decrypts and parses the dataset, passing each
+// entry to yield. Payloads in streaming envelopes are parsed as they are
+// decrypted, one entry at a time.
+func fetchDataset(datasetId *big.Int, yield func(DataEntry)) (Data, error) {
+	datares, err := getDataHash(datasetId)
+	if err != nil {
+		return Data{}, classifyErr("get data hash", err)
 	}
 
-	averageDataJson, err := json.Marshal(averageData)
+	body, err := openIPFS(datares.IPFSHash)
 	if err != nil {
-		log.Printf("Error marshalling average data: %v", err)
-		return
+		return Data{}, classifyErr("fetch dataset", err)
 	}
+	defer body.Close()
 
-	averageDataCID, err := addIPFS(string(averageDataJson))
+	meta, plaintext, err := decryptStream(body)
 	if err != nil {
-		log.Printf("Error adding average data to IPFS: %v", err)
-		return
+		return Data{}, classifyErr("fetch dataset", err)
+	}
+	data, err := decodePayload(meta.ContentType, plaintext, yield)
+	if err != nil {
+		return Data{}, classifyErr("parse dataset", err)
+	}
+	return data, nil
+}
+
+// computeResult builds the JSON pinned for the researcher from the
+// aggregate of each metric and the quality report. A dataset with nothing
+// left to aggregate fails the order rather than paying for an empty result.
+func computeResult(summaries map[string]metricSummary, report qualityReport) (string, error) {
+	if report.Accepted == 0 {
+		return "", permanentErr("compute result", fmt.Errorf("no valid entries: %s", report))
 	}
 
-	log.Printf("Average data CID: %s", averageDataCID)
+	result := map[string]interface{}{
+		"metrics": summaries,
+		"quality": report,
+	}
+	// Results before the metric registry only had these two averages;
+	// keep them for existing readers.
+	if s, ok := summaries[metricRegistry[MetricHeartRate].Name]; ok {
+		result["averageHeartRate"] = int64(s.Mean.(float64))
+	}
+	if s, ok := summaries[metricRegistry[MetricBloodOxygen].Name]; ok {
+		result["averageBloodOxygenLevel"] = s.Mean
+	}
 
-	err = completeOrder(order.OrderId, order.DatasetId, averageDataCID)
+	resultJson, err := json.Marshal(result)
 	if err != nil {
-		log.Printf("Error completing order: %v", err)
-		return
+		return "", fmt.Errorf("error marshalling result: %v", err)
 	}
+	return string(resultJson), nil
 }
 
 func readContract() (string, error) {
 	// read a contract method getDataset and input 0
-	log.Printf("Reading contract method getDataset")
-	cli, err := ethclient.Dial(RPC_URL)
-	if err != nil {
-		return "", err
-	}
-	escAbi, _ := abi.JSON(strings.NewReader(ABI_JSON))
-	input, _ := escAbi.Pack("getDataset", big.NewInt(0))
-	msg := ethereum.CallMsg{To: &CONTRACT_ADDR, Data: input}
-	out, err := cli.CallContract(context.Background(), msg, nil) // eth_call :contentReference[oaicite:4]{index=4}
+	ht, cli, err := dialContract()
 	if err != nil {
 		return "", err
 	}
-	log.Printf("out: %x", out)
+	defer cli.Close()
 
-	var data any
-	err = escAbi.UnpackIntoInterface(&data, "getDataset", out)
+	data, err := ht.GetDataset(&bind.CallOpts{Context: context.Background()}, big.NewInt(0))
 	if err != nil {
 		return "", err
 	}
@@ -243,8 +386,6 @@ func readContract() (string, error) {
 	}
 
 	// Convert JSON to string
-	jsonString := string(jsonData)
-	log.Printf("JSON string: %s", jsonString)
-	return jsonString, nil
+	return string(jsonData), nil
 
 }
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// MetricType is a health metric code as declared in a dataset's
+// healthMetricTypes on chain.
+type MetricType uint8
+
+const (
+	MetricBloodOxygen MetricType = iota
+	MetricHeartRate
+	MetricRespiratoryRate
+	MetricSteps
+	MetricSleepStage
+	MetricBloodPressure
+	MetricGlucose
+	MetricBodyTemperature
+	MetricHeartRateVariability
+	MetricWeight
+)
+
+// valueKind is the shape of a metric's values.
+type valueKind int
+
+const (
+	kindInteger  valueKind = iota // a whole number, e.g. steps
+	kindDecimal                   // any number, e.g. body temperature
+	kindCategory                  // one of metricDef.Categories
+	kindPair                      // named components, e.g. systolic and diastolic
+)
+
+// metricValue holds one measurement: a single number for integer and
+// decimal metrics, the index of the label for categories, and one number
+// per component for pairs.
+type metricValue []float64
+
+// metricDef describes a registered metric.
+type metricDef struct {
+	Type       MetricType
+	Name       string // key in payloads and results
+	Unit       string
+	Kind       valueKind
+	Ranges     []metricRange // plausible range of each component
+	Components []string      // component names of a kindPair metric
+	Categories []string      // labels of a kindCategory metric
+}
+
+// metricRegistry lists every metric the worker understands, by code. Codes
+// are part of the on-chain dataset format and must never be reused.
+var metricRegistry = map[MetricType]metricDef{
+	MetricBloodOxygen: {Name: "bloodOxygenLevel", Unit: "%", Kind: kindDecimal,
+		Ranges: []metricRange{{Min: 50, Max: 100}}},
+	MetricHeartRate: {Name: "heartRate", Unit: "bpm", Kind: kindInteger,
+		Ranges: []metricRange{{Min: 20, Max: 300}}},
+	MetricRespiratoryRate: {Name: "respiratoryRate", Unit: "breaths/min", Kind: kindDecimal,
+		Ranges: []metricRange{{Min: 4, Max: 60}}},
+	MetricSteps: {Name: "steps", Unit: "steps", Kind: kindInteger,
+		Ranges: []metricRange{{Min: 0, Max: 100_000}}},
+	MetricSleepStage: {Name: "sleepStage", Unit: "", Kind: kindCategory,
+		Categories: []string{"awake", "light", "deep", "rem"}},
+	MetricBloodPressure: {Name: "bloodPressure", Unit: "mmHg", Kind: kindPair,
+		Components: []string{"systolic", "diastolic"},
+		Ranges:     []metricRange{{Min: 50, Max: 260}, {Min: 20, Max: 160}}},
+	MetricGlucose: {Name: "glucose", Unit: "mg/dL", Kind: kindDecimal,
+		Ranges: []metricRange{{Min: 20, Max: 600}}},
+	MetricBodyTemperature: {Name: "bodyTemperature", Unit: "°C", Kind: kindDecimal,
+		Ranges: []metricRange{{Min: 30, Max: 45}}},
+	MetricHeartRateVariability: {Name: "heartRateVariability", Unit: "ms", Kind: kindDecimal,
+		Ranges: []metricRange{{Min: 1, Max: 300}}},
+	MetricWeight: {Name: "weight", Unit: "kg", Kind: kindDecimal,
+		Ranges: []metricRange{{Min: 1, Max: 500}}},
+}
+
+// metricsByName indexes metricRegistry by payload key. Building it fills in
+// the fields of metricRegistry that follow from the rest.
+var metricsByName = indexMetrics()
+
+func indexMetrics() map[string]metricDef {
+	byName := make(map[string]metricDef, len(metricRegistry))
+	for t, def := range metricRegistry {
+		def.Type = t
+		if def.Kind == kindCategory {
+			def.Ranges = []metricRange{{Min: 0, Max: float64(len(def.Categories) - 1)}}
+		}
+		metricRegistry[t] = def
+		byName[def.Name] = def
+	}
+	return byName
+}
+
+// declaredMetrics resolves a dataset's healthMetricTypes. An unknown code
+// means the dataset was registered for data this worker cannot check.
+func declaredMetrics(codes []uint8) ([]metricDef, error) {
+	defs := make([]metricDef, 0, len(codes))
+	for _, c := range codes {
+		def, ok := metricRegistry[MetricType(c)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported health metric type %d", c)
+		}
+		if !slices.ContainsFunc(defs, func(d metricDef) bool { return d.Type == def.Type }) {
+			defs = append(defs, def)
+		}
+	}
+	return defs, nil
+}
+
+// allMetrics returns every registered metric, by code. It stands in for the
+// declared metrics of datasets that declare none.
+func allMetrics() []metricDef {
+	defs := make([]metricDef, 0, len(metricRegistry))
+	for _, def := range metricsByName {
+		defs = append(defs, def)
+	}
+	slices.SortFunc(defs, func(a, b metricDef) int { return int(a.Type) - int(b.Type) })
+	return defs
+}
+
+// parse reads a payload value: a number for integer and decimal metrics, a
+// label for categories and an object with every component for pairs. Errors
+// name the metric and the expected shape, never the value, which is health
+// data.
+func (d metricDef) parse(raw json.RawMessage) (metricValue, error) {
+	switch d.Kind {
+	case kindInteger, kindDecimal:
+		var n json.Number
+		// json.Number also accepts quoted numbers; payloads must not quote them
+		if err := json.Unmarshal(raw, &n); err != nil || strings.HasPrefix(string(raw), `"`) {
+			return nil, fmt.Errorf("%s: want a number", d.Name)
+		}
+		v, err := n.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%s: number out of range", d.Name)
+		}
+		if d.Kind == kindInteger && v != math.Trunc(v) {
+			return nil, fmt.Errorf("%s: want an integer", d.Name)
+		}
+		return metricValue{v}, nil
+	case kindCategory:
+		var label string
+		if err := json.Unmarshal(raw, &label); err != nil {
+			return nil, fmt.Errorf("%s: want one of %s", d.Name, strings.Join(d.Categories, ", "))
+		}
+		i := slices.Index(d.Categories, strings.ToLower(label))
+		if i < 0 {
+			return nil, fmt.Errorf("%s: not one of %s", d.Name, strings.Join(d.Categories, ", "))
+		}
+		return metricValue{float64(i)}, nil
+	case kindPair:
+		var parts map[string]float64
+		if err := json.Unmarshal(raw, &parts); err != nil || len(parts) != len(d.Components) {
+			return nil, fmt.Errorf("%s: want {%s}", d.Name, strings.Join(d.Components, ", "))
+		}
+		v := make(metricValue, len(d.Components))
+		for i, c := range d.Components {
+			p, ok := parts[c]
+			if !ok {
+				return nil, fmt.Errorf("%s: missing %s", d.Name, c)
+			}
+			v[i] = p
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s: unknown value kind %d", d.Name, d.Kind)
+}
+
+// valid reports whether every component of v is within range.
+func (d metricDef) valid(v metricValue) bool {
+	if len(v) != len(d.Ranges) {
+		return false
+	}
+	for i, r := range d.Ranges {
+		if !r.contains(v[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// metricSummary is the aggregate of one metric pinned for the researcher.
+type metricSummary struct {
+	Unit  string `json:"unit,omitempty"`
+	Count int    `json:"count"`
+	// Mean, Min and Max are numbers, or component -> number for pairs.
+	Mean any `json:"mean,omitempty"`
+	Min  any `json:"min,omitempty"`
+	Max  any `json:"max,omitempty"`
+	// Distribution counts each label of a category metric.
+	Distribution map[string]int `json:"distribution,omitempty"`
+}
+
+// metricStats aggregates the values of one metric as they are accepted, so
+// only running totals are kept, never the values themselves.
+type metricStats struct {
+	def          metricDef
+	count        int
+	sum, lo, hi  []float64
+	distribution map[string]int // per label, for categories
+}
+
+func newMetricStats(d metricDef) *metricStats {
+	n := len(d.Ranges)
+	s := &metricStats{def: d, sum: make([]float64, n), lo: make([]float64, n), hi: make([]float64, n)}
+	for i := range s.lo {
+		s.lo[i], s.hi[i] = math.Inf(1), math.Inf(-1)
+	}
+	if d.Kind == kindCategory {
+		s.distribution = make(map[string]int)
+	}
+	return s
+}
+
+func (s *metricStats) add(v metricValue) {
+	s.count++
+	if s.def.Kind == kindCategory {
+		s.distribution[s.def.Categories[int(v[0])]]++
+		return
+	}
+	for i, x := range v {
+		s.sum[i] += x
+		s.lo[i], s.hi[i] = min(s.lo[i], x), max(s.hi[i], x)
+	}
+}
+
+// summary returns the aggregate of the values added so far.
+func (s *metricStats) summary() metricSummary {
+	out := metricSummary{Unit: s.def.Unit, Count: s.count}
+	if s.def.Kind == kindCategory {
+		out.Distribution = s.distribution
+		return out
+	}
+	mean := make([]float64, len(s.sum))
+	for i := range s.sum {
+		mean[i] = s.sum[i] / float64(s.count)
+	}
+	out.Mean, out.Min, out.Max = s.def.shape(mean), s.def.shape(s.lo), s.def.shape(s.hi)
+	return out
+}
+
+// shape presents per-component numbers the way the metric's values look in
+// payloads: a bare number, or an object keyed by component.
+func (d metricDef) shape(v []float64) any {
+	if d.Kind != kindPair {
+		return v[0]
+	}
+	m := make(map[string]float64, len(v))
+	for i, c := range d.Components {
+		m[c] = v[i]
+	}
+	return m
+}
@@ -2,281 +2,54 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
-	"os"
-	"reflect"
-	"strings"
 
-	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
-func getStake(orderid uint64, datasetid uint64) (Order, error) {
-	cli, err := ethclient.Dial(RPC_URL)
-	if err != nil {
-		return Order{}, err
-	}
-	escAbi, _ := abi.JSON(strings.NewReader(ABI_JSON))
-	input, _ := escAbi.Pack("getStake", big.NewInt(int64(datasetid)), big.NewInt(int64(orderid)))
-	msg := ethereum.CallMsg{To: &CONTRACT_ADDR, Data: input}
-	out, err := cli.CallContract(context.Background(), msg, nil)
+// errOrderNotFound is returned by getStake when the contract has no order
+// under the requested ids: the mapping lookup yields a zeroed struct.
+var errOrderNotFound = errors.New("order not found")
+
+// getStake reads the order from the contract and checks that it is the one
+// that was asked for.
+func getStake(orderid *big.Int, datasetid *big.Int) (Order, error) {
+	ht, cli, err := dialContract()
 	if err != nil {
 		return Order{}, err
 	}
+	defer cli.Close()
 
-	// Unpack the raw return values into variables
-	unpacked, err := escAbi.Unpack("getStake", out)
+	o, err := ht.GetStake(&bind.CallOpts{Context: context.Background()}, datasetid, orderid)
 	if err != nil {
 		return Order{}, err
 	}
 
-	// Check if the returned data is what we expect
-	if len(unpacked) == 0 {
-		return Order{}, fmt.Errorf("no data returned from contract")
+	// orderRequest always records msg.sender and block.timestamp, so a zero
+	// researcher or timestamp means the slot was never written.
+	if o.Researcher == (common.Address{}) || o.Timestamp.Sign() == 0 {
+		return Order{}, permanentErr("get order", fmt.Errorf("%w: order %d on dataset %d", errOrderNotFound, orderid, datasetid))
 	}
-
-	// Extract values by accessing the tuple directly
-	// The values appear to be in a map or struct of some kind
-	orderMap, ok := unpacked[0].(map[string]interface{})
-	if ok {
-		// If it's a map, extract fields by name
-		return Order{
-			OrderId:      orderMap["OrderId"].(*big.Int).Uint64(),
-			DatasetId:    orderMap["DatasetId"].(*big.Int).Uint64(),
-			Researcher:   orderMap["Researcher"].(common.Address).Hex(),
-			Patient:      orderMap["Patient"].(common.Address).Hex(),
-			Amount:       orderMap["Amount"].(*big.Int).Uint64(),
-			TokenAddress: orderMap["TokenAddress"].(common.Address).Hex(),
-			Timestamp:    orderMap["Timestamp"].(*big.Int).Uint64(),
-			Completed:    orderMap["Completed"].(bool),
-		}, nil
-	}
-
-	// Since it's failing type assertion but we can see the data is there,
-	// let's try a different approach by directly examining what's in unpacked[0]
-	fmt.Printf("Type of unpacked[0]: %T\n", unpacked[0])
-
-	// If it's not a map, maybe it's a struct
-	// Let's try to access it by index if it's a slice/array
-	tupleData, isTuple := unpacked[0].([]interface{})
-	if isTuple && len(tupleData) >= 8 {
-		return Order{
-			OrderId:      tupleData[0].(*big.Int).Uint64(),
-			DatasetId:    tupleData[1].(*big.Int).Uint64(),
-			Researcher:   tupleData[2].(common.Address).Hex(),
-			Patient:      tupleData[3].(common.Address).Hex(),
-			Amount:       tupleData[4].(*big.Int).Uint64(),
-			TokenAddress: tupleData[5].(common.Address).Hex(),
-			Timestamp:    tupleData[6].(*big.Int).Uint64(),
-			Completed:    tupleData[7].(bool),
-		}, nil
+	if o.OrderId.Cmp(orderid) != 0 || o.DatasetId.Cmp(datasetid) != 0 {
+		return Order{}, permanentErr("get order", fmt.Errorf("contract returned order %s on dataset %s, expected order %d on dataset %d",
+			o.OrderId, o.DatasetId, orderid, datasetid))
 	}
 
-	// As a last resort, use reflection to extract the values
-	// This is a bit of a hack, but it should work if the object has the right fields
-	objValue := reflect.ValueOf(unpacked[0])
-	if objValue.Kind() == reflect.Struct {
-		return Order{
-			OrderId:      objValue.FieldByName("OrderId").Interface().(*big.Int).Uint64(),
-			DatasetId:    objValue.FieldByName("DatasetId").Interface().(*big.Int).Uint64(),
-			Researcher:   objValue.FieldByName("Researcher").Interface().(common.Address).Hex(),
-			Patient:      objValue.FieldByName("Patient").Interface().(common.Address).Hex(),
-			Amount:       objValue.FieldByName("Amount").Interface().(*big.Int).Uint64(),
-			TokenAddress: objValue.FieldByName("TokenAddress").Interface().(common.Address).Hex(),
-			Timestamp:    objValue.FieldByName("Timestamp").Interface().(*big.Int).Uint64(),
-			Completed:    objValue.FieldByName("Completed").Interface().(bool),
-		}, nil
-	}
-
-	// Special debug handling to directly use the error values
-	if _, ok := unpacked[0].(interface{}); ok {
-		// The error message shows us exactly what's in the data
-		// {0 0 0x2346ac3Bc15656D4dE1da99384B5498A75f128a2 0x2346ac3Bc15656D4dE1da99384B5498A75f128a2 10000000000000000 0x96B327504934Be375d5EC1F88a8B2Bba0FaC63C7 1746854081 false}
-
-		// Just create a hard-coded Order from the data we see in the error message
-		return Order{
-			OrderId:      orderid,   // Use the input orderid since it's 0 in response
-			DatasetId:    datasetid, // Use the input datasetid since it's 0 in response
-			Researcher:   "0x2346ac3Bc15656D4dE1da99384B5498A75f128a2",
-			Patient:      "0x2346ac3Bc15656D4dE1da99384B5498A75f128a2",
-			Amount:       10000000000000000,
-			TokenAddress: "0x96B327504934Be375d5EC1F88a8B2Bba0FaC63C7",
-			Timestamp:    1746854081,
-			Completed:    false,
-		}, nil
-	}
-
-	// If all else fails, return the error
-	return Order{}, fmt.Errorf("could not decode return data: %v", unpacked[0])
+	return Order{
+		OrderId:      o.OrderId,
+		DatasetId:    o.DatasetId,
+		Researcher:   o.Researcher.Hex(),
+		Patient:      o.Patient.Hex(),
+		Amount:       o.Amount,
+		TokenAddress: o.TokenAddress.Hex(),
+		Timestamp:    o.Timestamp,
+		Completed:    o.Completed,
+	}, nil
 }
 
-func completeOrder(orderId uint64, datasetId uint64, ipfsHash string) error {
-	// Connect to Ethereum client
-	cli, err := ethclient.Dial(RPC_URL)
-	if err != nil {
-		return err
-	}
-
-	// Load the private key
-	privateKey, err := crypto.HexToECDSA(os.Getenv("PRIVATE_KEY"))
-	if err != nil {
-		return fmt.Errorf("invalid private key: %v", err)
-	}
-
-	// Get the account address from the private key
-	publicKeyECDSA, ok := privateKey.Public().(*ecdsa.PublicKey)
-	if !ok {
-		return fmt.Errorf("error casting public key to ECDSA")
-	}
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-
-	// Get the nonce for the account
-	nonce, err := cli.PendingNonceAt(context.Background(), fromAddress)
-	if err != nil {
-		return fmt.Errorf("failed to get nonce: %v", err)
-	}
-
-	// Get suggested gas price
-	gasPrice, err := cli.SuggestGasPrice(context.Background())
-	if err != nil {
-		return fmt.Errorf("failed to suggest gas price: %v", err)
-	}
-
-	// Create the ABI
-	escAbi, err := abi.JSON(strings.NewReader(ABI_JSON))
-	if err != nil {
-		return fmt.Errorf("failed to parse ABI: %v", err)
-	}
-
-	// Pack the data
-	input, err := escAbi.Pack("completeOrder", big.NewInt(int64(datasetId)), big.NewInt(int64(orderId)), ipfsHash)
-	if err != nil {
-		return fmt.Errorf("failed to pack data: %v", err)
-	}
-
-	// Estimate gas limit
-	gasLimit, err := cli.EstimateGas(context.Background(), ethereum.CallMsg{
-		From: fromAddress,
-		To:   &CONTRACT_ADDR,
-		Data: input,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to estimate gas: %v", err)
-	}
-	// Add a buffer to the gas limit
-	gasLimit = gasLimit * 120 / 100
-
-	// Create the transaction
-	tx := types.NewTransaction(
-		nonce,
-		CONTRACT_ADDR,
-		big.NewInt(0), // No ETH being sent
-		gasLimit,
-		gasPrice,
-		input,
-	)
-
-	// Get chainID
-	chainID, err := cli.NetworkID(context.Background())
-	if err != nil {
-		return fmt.Errorf("failed to get chain ID: %v", err)
-	}
-
-	// Sign the transaction
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
-	if err != nil {
-		return fmt.Errorf("failed to sign transaction: %v", err)
-	}
-
-	// Send the transaction
-	err = cli.SendTransaction(context.Background(), signedTx)
-	if err != nil {
-		return fmt.Errorf("failed to send transaction: %v", err)
-	}
-
-	// Wait for transaction receipt
-	fmt.Printf("Transaction sent: %s\n", signedTx.Hash().Hex())
-	receipt, err := bind.WaitMined(context.Background(), cli, signedTx)
-	if err != nil {
-		return fmt.Errorf("failed to get transaction receipt: %v", err)
-	}
-
-	if receipt.Status == 0 {
-		return fmt.Errorf("transaction failed")
-	}
-
-	return nil
+func completeOrder(orderId *big.Int, datasetId *big.Int, ipfsHash string) error {
+	return txs.Submit(context.Background(), "completeOrder", datasetId, orderId, ipfsHash)
 }
-
-// const (
-// 	baseURL         = "http://localhost"
-// 	endpointPath    = "/rofl/v1/tx/sign-submit"
-// 	defaultGasLimit = 200_000
-// )
-
-// func CompleteOrder(ctx context.Context, datasetID, orderID *big.Int) error {
-
-// 	//------------------------------------------------------------
-// 	// 1) ABI‑encode the function call
-// 	//------------------------------------------------------------
-// 	parsed, err := abi.JSON(bytes.NewReader([]byte(
-// 		`[{"inputs":[{"internalType":"uint256","name":"datasetId","type":"uint256"},
-// 		             {"internalType":"uint256","name":"orderId" , "type":"uint256"}],
-// 		    "name":"completeOrder","outputs":[],"stateMutability":"nonpayable","type":"function"}]`,
-// 	)))
-// 	if err != nil {
-// 		return fmt.Errorf("parse ABI: %w", err)
-// 	}
-// 	data, err := parsed.Pack("completeOrder", datasetID, orderID)
-// 	if err != nil {
-// 		return fmt.Errorf("pack arguments: %w", err)
-// 	}
-
-// 	//------------------------------------------------------------
-// 	// 2) Build the JSON body the endpoint expects
-// 	//------------------------------------------------------------
-// 	body := map[string]any{
-// 		"tx": map[string]any{
-// 			"kind": "eth",
-// 			"data": map[string]any{
-// 				"gas_limit": defaultGasLimit,
-// 				"to":        CONTRACT_ADDR,
-// 				"value":     0,
-// 				"data":      hexutil.Encode(data), // 0x‑prefixed
-// 			},
-// 		},
-// 	}
-// 	raw, err := json.Marshal(body)
-// 	if err != nil {
-// 		return fmt.Errorf("marshal request body: %w", err)
-// 	}
-
-// 	//------------------------------------------------------------
-// 	// 3) Fire the POST request
-// 	//------------------------------------------------------------
-// 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+endpointPath, bytes.NewReader(raw))
-// 	if err != nil {
-// 		return fmt.Errorf("new request: %w", err)
-// 	}
-// 	req.Header.Set("Content-Type", "application/json")
-
-// 	// HTTP client with a sensible timeout
-// 	client := &http.Client{Timeout: 15 * time.Second}
-// 	resp, err := client.Do(req)
-// 	if err != nil {
-// 		return fmt.Errorf("POST %s: %w", endpointPath, err)
-// 	}
-// 	defer resp.Body.Close()
-
-// 	if resp.StatusCode != http.StatusOK {
-// 		return fmt.Errorf("endpoint returned %s", resp.Status)
-// 	}
-// 	return nil
-// }
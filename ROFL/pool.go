@@ -0,0 +1,190 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// workerPool runs computeHandler on a fixed number of workers fed by a
+// bounded queue ordered by order deadline, so the orders closest to expiring
+// run first. Submit blocks while the queue is full, which pushes back on log
+// ingestion instead of piling up goroutines.
+type workerPool struct {
+	workers int
+
+	mu    sync.Mutex
+	queue jobQueue
+	slots chan struct{} // holds one token per occupied queue slot
+	ready chan struct{} // holds one token per queued job
+
+	busy atomic.Int32
+	// average job duration in nanoseconds, used to estimate queue delay
+	avgDuration atomic.Int64
+}
+
+func newWorkerPool(workers, queueSize int) *workerPool {
+	queueSize = max(queueSize, 1)
+	p := &workerPool{
+		workers: max(workers, 1),
+		slots:   make(chan struct{}, queueSize),
+		ready:   make(chan struct{}, queueSize),
+	}
+	p.avgDuration.Store(int64(time.Minute))
+	return p
+}
+
+// Start launches the workers; they exit once ctx is cancelled.
+func (p *workerPool) Start(ctx context.Context, fn func(Job)) {
+	for i := 0; i < p.workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-p.ready:
+				}
+
+				p.mu.Lock()
+				if p.queue.Len() == 0 {
+					// token left behind by Cancel
+					p.mu.Unlock()
+					continue
+				}
+				job := heap.Pop(&p.queue).(Job)
+				p.mu.Unlock()
+				<-p.slots
+
+				p.busy.Add(1)
+				start := time.Now()
+				fn(job)
+				p.observe(time.Since(start))
+				p.busy.Add(-1)
+			}
+		}()
+	}
+	go p.report(ctx)
+}
+
+// Submit enqueues job, waiting for room if the queue is full.
+func (p *workerPool) Submit(ctx context.Context, job Job) error {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		slog.Warn("Queue full, waiting to queue order", "orderId", job.OrderId, "datasetId", job.DatasetId, "depth", p.Depth(), "capacity", cap(p.slots))
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	p.mu.Lock()
+	heap.Push(&p.queue, job)
+	p.mu.Unlock()
+	p.ready <- struct{}{}
+
+	slog.Info("Queued order", "orderId", job.OrderId, "datasetId", job.DatasetId, "deadline", job.Deadline, "depth", p.Depth(), "capacity", cap(p.slots))
+	return nil
+}
+
+// Cancel removes the job with the given key if it is still waiting for a
+// worker. It reports false when the job is not queued, e.g. because a
+// worker already started it.
+func (p *workerPool) Cancel(key string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, job := range p.queue {
+		if job.Key() == key {
+			heap.Remove(&p.queue, i)
+			<-p.slots
+			return true
+		}
+	}
+	return false
+}
+
+// Depth is the number of jobs waiting for a worker.
+func (p *workerPool) Depth() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queue.Len()
+}
+
+// observe folds d into the running average job duration.
+func (p *workerPool) observe(d time.Duration) {
+	avg := time.Duration(p.avgDuration.Load())
+	p.avgDuration.Store(int64(avg*4/5 + d/5))
+}
+
+// report periodically logs queue depth and worker usage while there is work,
+// and warns about queued orders that will likely expire before a worker
+// reaches them.
+func (p *workerPool) report(ctx context.Context) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if depth, busy := p.Depth(), p.busy.Load(); depth > 0 || busy > 0 {
+				slog.Info("Worker pool", "busy", busy, "workers", p.workers, "depth", depth, "capacity", cap(p.slots))
+			}
+			p.warnAtRisk()
+		}
+	}
+}
+
+func (p *workerPool) warnAtRisk() {
+	p.mu.Lock()
+	queued := p.queue.Sorted()
+	p.mu.Unlock()
+
+	avg := time.Duration(p.avgDuration.Load())
+	for i, job := range queued {
+		// jobs ahead of this one are spread over all workers
+		start := time.Duration(i/p.workers+1) * avg
+		if left := time.Until(job.Deadline) - expiryMargin; start > left {
+			slog.Warn("Order at risk of expiring", "orderId", job.OrderId, "datasetId", job.DatasetId,
+				"deadline", job.Deadline, "estimatedStart", start.Round(time.Second))
+		}
+	}
+}
+
+// jobQueue is a min-heap of jobs by deadline, oldest first on ties.
+type jobQueue []Job
+
+func (q jobQueue) Len() int { return len(q) }
+
+func (q jobQueue) Less(a, b int) bool {
+	if !q[a].Deadline.Equal(q[b].Deadline) {
+		return q[a].Deadline.Before(q[b].Deadline)
+	}
+	return q[a].CreatedAt.Before(q[b].CreatedAt)
+}
+
+func (q jobQueue) Swap(a, b int) { q[a], q[b] = q[b], q[a] }
+
+func (q *jobQueue) Push(x any) { *q = append(*q, x.(Job)) }
+
+func (q *jobQueue) Pop() any {
+	old := *q
+	job := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return job
+}
+
+// Sorted returns a copy of the queue in the order jobs will run.
+func (q jobQueue) Sorted() []Job {
+	c := append(jobQueue(nil), q...)
+	out := make([]Job, 0, len(c))
+	for c.Len() > 0 {
+		out = append(out, heap.Pop(&c).(Job))
+	}
+	return out
+}
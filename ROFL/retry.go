@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+var (
+	maxAttempts    = envInt("MAX_ATTEMPTS", 5)
+	retryBaseDelay = time.Duration(envInt("RETRY_BASE_SECONDS", 10)) * time.Second
+	retryMaxDelay  = time.Duration(envInt("RETRY_MAX_SECONDS", 600)) * time.Second
+)
+
+// retryDelay is the backoff before attempt number attempt+1: exponential in
+// the attempts made so far, capped at retryMaxDelay and jittered by ±20% so
+// jobs that failed together do not retry together.
+func retryDelay(attempts int) time.Duration {
+	d := retryBaseDelay
+	for i := 1; i < attempts && d < retryMaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, retryMaxDelay)
+	jitter := time.Duration(rand.Int64N(int64(d)/5*2+1)) - d/5
+	return d + jitter
+}
+
+// scheduleJob queues job once its NextAttempt has passed.
+func scheduleJob(ctx context.Context, job Job) {
+	delay := time.Until(job.NextAttempt)
+	if delay <= 0 {
+		if err := pool.Submit(ctx, job); err != nil {
+			slog.Error("Error queueing order", "orderId", job.OrderId, "datasetId", job.DatasetId, "err", err)
+		}
+		return
+	}
+
+	time.AfterFunc(delay, func() {
+		if err := pool.Submit(ctx, job); err != nil {
+			slog.Error("Error queueing order", "orderId", job.OrderId, "datasetId", job.DatasetId, "err", err)
+		}
+	})
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Dataset payloads are JSON documents that open with their schema version:
+//
+//	{"schemaVersion": 2, "owner": "0x…", "entries": [{"timestamp": …, …}, …]}
+//
+// schemaVersion must be the first member, so the decoder can be chosen
+// before the rest of the document is read. Payloads from before versioning
+// are JS-style object literals or bare arrays and have no version.
+const schemaLegacy = 0
+
+// A datasetDecoder parses dataset payloads of one schema version, passing
+// each entry to yield as it is read.
+type datasetDecoder interface {
+	Decode(r io.Reader, yield func(DataEntry)) (Data, error)
+}
+
+var datasetDecoders = map[int]datasetDecoder{
+	schemaLegacy: legacyDecoder{},
+	1:            strictDecoderV1,
+	2:            strictDecoderV2,
+}
+
+// schemaPeek is how far into a payload the schema version is looked for.
+const schemaPeek = 256
+
+// decodeDataset parses a decrypted payload with the decoder for its schema
+// version. Malformed payloads are permanent errors; read errors keep their
+// own classification.
+func decodeDataset(r io.Reader, yield func(DataEntry)) (Data, error) {
+	br := bufio.NewReaderSize(r, schemaPeek)
+	version, err := peekSchemaVersion(br)
+	if err != nil {
+		return Data{}, err
+	}
+	dec, ok := datasetDecoders[version]
+	if !ok {
+		return Data{}, permanentErr("parse dataset", fmt.Errorf("unsupported schemaVersion %d", version))
+	}
+	return dec.Decode(br, yield)
+}
+
+// peekSchemaVersion reads the schema version from the start of br without
+// consuming anything. Anything that is not a JSON object is legacy.
+func peekSchemaVersion(br *bufio.Reader) (int, error) {
+	head, err := br.Peek(schemaPeek)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return 0, err
+	}
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return schemaLegacy, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+	tokens := make([]json.Token, 0, 3)
+	for len(tokens) < 3 {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		tokens = append(tokens, tok)
+	}
+	if len(tokens) < 3 || tokens[1] != "schemaVersion" {
+		return 0, permanentErr("parse dataset", errors.New("payload must start with schemaVersion"))
+	}
+	n, ok := tokens[2].(json.Number)
+	if !ok {
+		return 0, permanentErr("parse dataset", fmt.Errorf("schemaVersion must be a number, got %v", tokens[2]))
+	}
+	version, err := n.Int64()
+	if err != nil || version <= schemaLegacy {
+		return 0, permanentErr("parse dataset", fmt.Errorf("invalid schemaVersion %s", n))
+	}
+	return int(version), nil
+}
+
+// strictDecoder parses one schema version. Every entry field is required,
+// unknown members are rejected and nothing may follow the document. Entries
+// are decoded one at a time as the payload is read, by entry.
+type strictDecoder struct {
+	version int
+	entry   func(dec *json.Decoder) (DataEntry, error)
+}
+
+// strictDecoderV1 reads entries with fixed heartRate and bloodOxygenLevel
+// fields.
+var strictDecoderV1 = strictDecoder{version: 1, entry: decodeEntryV1}
+
+// strictDecoderV2 reads entries carrying any registered metric:
+//
+//	{"timestamp": …, "metrics": {"heartRate": 61, "sleepStage": "deep",
+//	  "bloodPressure": {"systolic": 118, "diastolic": 76}}}
+var strictDecoderV2 = strictDecoder{version: 2, entry: decodeEntryV2}
+
+func (s strictDecoder) Decode(r io.Reader, yield func(DataEntry)) (Data, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	dec.UseNumber()
+
+	var d Data
+	if err := expectDelim(dec, '{'); err != nil {
+		return d, err
+	}
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return d, syntaxOrReadErr(err)
+		}
+		key, _ := tok.(string)
+		if seen[key] {
+			return d, permanentErr("parse dataset", fmt.Errorf("duplicate member %q", key))
+		}
+		seen[key] = true
+
+		switch key {
+		case "schemaVersion":
+			var n json.Number
+			if err := dec.Decode(&n); err != nil {
+				return d, syntaxOrReadErr(err)
+			}
+			if n.String() != strconv.Itoa(s.version) {
+				return d, permanentErr("parse dataset", fmt.Errorf("schemaVersion %s in a version %d document", n, s.version))
+			}
+			d.SchemaVersion = s.version
+		case "owner":
+			if err := dec.Decode(&d.Owner); err != nil {
+				return d, syntaxOrReadErr(err)
+			}
+		case "entries":
+			if err := s.decodeEntries(dec, yield); err != nil {
+				return d, err
+			}
+		default:
+			return d, permanentErr("parse dataset", fmt.Errorf("unknown member %q", key))
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return d, err
+	}
+	if !seen["entries"] {
+		return d, permanentErr("parse dataset", errors.New("missing entries"))
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return d, syntaxOrReadErr(err)
+		}
+		return d, permanentErr("parse dataset", errors.New("data after the document"))
+	}
+	return d, nil
+}
+
+func (s strictDecoder) decodeEntries(dec *json.Decoder, yield func(DataEntry)) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for i := 0; dec.More(); i++ {
+		e, err := s.entry(dec)
+		if err != nil {
+			var pe *pipelineError
+			if errors.As(err, &pe) {
+				pe.Err = fmt.Errorf("entry %d: %w", i, pe.Err)
+				return pe
+			}
+			return syntaxOrReadErr(fmt.Errorf("entry %d: %w", i, err))
+		}
+		yield(e)
+	}
+	return expectDelim(dec, ']')
+}
+
+// entryV1 is a version 1 entry as it appears on the wire; pointers tell a
+// missing field from a zero one.
+type entryV1 struct {
+	Timestamp        *int64   `json:"timestamp"`
+	HeartRate        *int64   `json:"heartRate"`
+	BloodOxygenLevel *float64 `json:"bloodOxygenLevel"`
+}
+
+func decodeEntryV1(dec *json.Decoder) (DataEntry, error) {
+	var e entryV1
+	if err := dec.Decode(&e); err != nil {
+		return DataEntry{}, typeErr(err)
+	}
+	var missing []string
+	if e.Timestamp == nil {
+		missing = append(missing, "timestamp")
+	}
+	if e.HeartRate == nil {
+		missing = append(missing, "heartRate")
+	}
+	if e.BloodOxygenLevel == nil {
+		missing = append(missing, "bloodOxygenLevel")
+	}
+	if len(missing) > 0 {
+		return DataEntry{}, permanentErr("parse dataset", fmt.Errorf("missing %s", strings.Join(missing, ", ")))
+	}
+	return heartRateEntry(*e.Timestamp, *e.HeartRate, *e.BloodOxygenLevel), nil
+}
+
+type entryV2 struct {
+	Timestamp *int64                     `json:"timestamp"`
+	Metrics   map[string]json.RawMessage `json:"metrics"`
+}
+
+func decodeEntryV2(dec *json.Decoder) (DataEntry, error) {
+	var e entryV2
+	if err := dec.Decode(&e); err != nil {
+		return DataEntry{}, typeErr(err)
+	}
+	if e.Timestamp == nil {
+		return DataEntry{}, permanentErr("parse dataset", errors.New("missing timestamp"))
+	}
+	if len(e.Metrics) == 0 {
+		return DataEntry{}, permanentErr("parse dataset", errors.New("no metrics"))
+	}
+	entry := DataEntry{Timestamp: *e.Timestamp, Values: make(map[MetricType]metricValue, len(e.Metrics))}
+	for name, raw := range e.Metrics {
+		def, ok := metricsByName[name]
+		if !ok {
+			return DataEntry{}, permanentErr("parse dataset", fmt.Errorf("unknown metric %q", name))
+		}
+		v, err := def.parse(raw)
+		if err != nil {
+			return DataEntry{}, permanentErr("parse dataset", err)
+		}
+		entry.Values[def.Type] = v
+	}
+	return entry, nil
+}
+
+// heartRateEntry builds an entry from the two metrics schema versions
+// before 2 carry.
+func heartRateEntry(timestamp, heartRate int64, bloodOxygenLevel float64) DataEntry {
+	return DataEntry{Timestamp: timestamp, Values: map[MetricType]metricValue{
+		MetricHeartRate:   {float64(heartRate)},
+		MetricBloodOxygen: {bloodOxygenLevel},
+	}}
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return syntaxOrReadErr(err)
+	}
+	if tok != want {
+		// tok may be a value from the payload; leave it out
+		return permanentErr("parse dataset", fmt.Errorf("expected %v", want))
+	}
+	return nil
+}
+
+// syntaxOrReadErr marks JSON errors as permanent and passes read errors,
+// such as a cut-off download, through unchanged.
+func syntaxOrReadErr(err error) error {
+	err = typeErr(err)
+	var se *json.SyntaxError
+	var te *jsonTypeError
+	// DisallowUnknownFields reports unknown fields as a plain error
+	if errors.As(err, &se) || errors.As(err, &te) || strings.Contains(err.Error(), "json: unknown field") {
+		return permanentErr("parse dataset", err)
+	}
+	return err
+}
+
+// jsonTypeError is a json.UnmarshalTypeError without the value: encoding/json
+// quotes the offending number in its message, and that number is health
+// data.
+type jsonTypeError struct {
+	Field string // dotted path, empty at the top level
+	Want  string // Go type
+	Got   string // JSON kind, e.g. "number"
+}
+
+func (e *jsonTypeError) Error() string {
+	msg := fmt.Sprintf("want %s, got a JSON %s", e.Want, e.Got)
+	if e.Field != "" {
+		msg = e.Field + ": " + msg
+	}
+	return msg
+}
+
+// typeErr replaces a json.UnmarshalTypeError with a jsonTypeError and
+// returns other errors unchanged. Call it before adding context to err.
+func typeErr(err error) error {
+	var te *json.UnmarshalTypeError
+	if !errors.As(err, &te) {
+		return err
+	}
+	kind, _, _ := strings.Cut(te.Value, " ") // "number 72.5" -> "number"
+	return &jsonTypeError{Field: te.Field, Want: te.Type.String(), Got: kind}
+}
+
+// legacyDecoder parses the payloads written before schema versions: a
+// JSON array of entries, or the JS-style literal the first web client
+// produced, with unquoted keys and the array itself quoted. The repairs
+// below are plain text substitutions and can misfire on unusual values,
+// which is why they are confined to unversioned payloads.
+type legacyDecoder struct{}
+
+func (legacyDecoder) Decode(r io.Reader, yield func(DataEntry)) (Data, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return Data{}, err
+	}
+	text := string(raw)
+
+	// Remove outer quotes if needed
+	fixedText := strings.ReplaceAll(text, "\"[", "[")
+	fixedText = strings.ReplaceAll(fixedText, "]\"", "]")
+
+	// Fix missing quotes around timestamp and ensure it's consistent
+	fixedText = strings.ReplaceAll(fixedText, "{timestamp:", "{\"timestamp\":")
+	fixedText = strings.ReplaceAll(fixedText, "{ timestamp:", "{\"timestamp\":")
+
+	// Fix other fields
+	fixedText = strings.ReplaceAll(fixedText, ", heartRate:", ", \"heartRate\":")
+	fixedText = strings.ReplaceAll(fixedText, ", bloodOxygenLevel:", ", \"bloodOxygenLevel\":")
+
+	// Join objects written back to back
+	fixedText = strings.ReplaceAll(fixedText, "}{", " },{")
+
+	// The repaired text is decoded one entry at a time, so only the text
+	// is held in memory, not the entries as well.
+	if err := decodeLegacyEntries(json.NewDecoder(strings.NewReader(fixedText)), yield); err != nil {
+		// the whole payload has been read, so even running out of text
+		// means it is malformed rather than cut off
+		var pe *pipelineError
+		if !errors.As(err, &pe) {
+			err = permanentErr("parse dataset", err)
+		}
+		return Data{}, err
+	}
+	return Data{SchemaVersion: schemaLegacy}, nil
+}
+
+func decodeLegacyEntries(dec *json.Decoder, yield func(DataEntry)) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for i := 0; dec.More(); i++ {
+		var e struct {
+			Timestamp        int64   `json:"timestamp"`
+			HeartRate        int64   `json:"heartRate"`
+			BloodOxygenLevel float64 `json:"bloodOxygenLevel"`
+		}
+		if err := dec.Decode(&e); err != nil {
+			return fmt.Errorf("entry %d: %w", i, typeErr(err))
+		}
+		yield(heartRateEntry(e.Timestamp, e.HeartRate, e.BloodOxygenLevel))
+	}
+	if err := expectDelim(dec, ']'); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("data after the entries")
+	}
+	return nil
+}
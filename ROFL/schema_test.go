@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// decodeAll decodes payload with decodeDataset and collects its entries.
+func decodeAll(payload string) (Data, []DataEntry, error) {
+	var entries []DataEntry
+	data, err := decodeDataset(strings.NewReader(payload), func(e DataEntry) {
+		entries = append(entries, e)
+	})
+	return data, entries, err
+}
+
+func TestPeekSchemaVersion(t *testing.T) {
+	for _, tc := range []struct {
+		payload string
+		want    int
+		wantErr string
+	}{
+		{`[{"timestamp":1}]`, schemaLegacy, ""},
+		{`"[{timestamp: 1}]"`, schemaLegacy, ""},
+		{``, schemaLegacy, ""},
+		{" \n\t{\"schemaVersion\": 2, \"entries\": []}", 2, ""},
+		{`{"schemaVersion":1}`, 1, ""},
+		{`{"entries":[],"schemaVersion":2}`, 0, "payload must start with schemaVersion"},
+		{`{"schemaVersion":"2"}`, 0, "schemaVersion must be a number"},
+		{`{"schemaVersion":1.5}`, 0, "invalid schemaVersion"},
+		{`{"schemaVersion":0}`, 0, "invalid schemaVersion"},
+		{`{"schemaVersion":-1}`, 0, "invalid schemaVersion"},
+	} {
+		br := bufio.NewReaderSize(strings.NewReader(tc.payload), schemaPeek)
+		got, err := peekSchemaVersion(br)
+		if tc.wantErr != "" {
+			if err == nil || isTransient(err) || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: got %v, want a permanent error containing %q", tc.payload, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got version %d, err %v; want %d", tc.payload, got, err, tc.want)
+		}
+		if n := br.Buffered(); n != len(tc.payload) {
+			t.Errorf("%s: consumed %d bytes", tc.payload, len(tc.payload)-n)
+		}
+	}
+}
+
+func TestDecodeDatasetAccepts(t *testing.T) {
+	heartRate := func(ts, hr int64, spo2 float64) DataEntry { return heartRateEntry(ts, hr, spo2) }
+	for _, tc := range []struct {
+		name    string
+		payload string
+		version int
+		owner   string
+		want    []DataEntry
+	}{
+		{"version 1",
+			`{"schemaVersion":1,"owner":"0xabc","entries":[
+				{"timestamp":1700000000,"heartRate":72,"bloodOxygenLevel":97.5},
+				{"timestamp":1700000060,"heartRate":75,"bloodOxygenLevel":98}]}`,
+			1, "0xabc", []DataEntry{heartRate(1700000000, 72, 97.5), heartRate(1700000060, 75, 98)}},
+		{"version 2",
+			`{"schemaVersion":2,"entries":[{"timestamp":1700000000,"metrics":{
+				"heartRate":61,"sleepStage":"Deep","bloodPressure":{"systolic":118,"diastolic":76}}}]}`,
+			2, "", []DataEntry{{Timestamp: 1700000000, Values: map[MetricType]metricValue{
+				MetricHeartRate:     {61},
+				MetricSleepStage:    {2},
+				MetricBloodPressure: {118, 76},
+			}}}},
+		{"version 2 without entries",
+			`{"schemaVersion":2,"entries":[]}`, 2, "", nil},
+		{"legacy array",
+			`[{"timestamp":1700000000,"heartRate":72,"bloodOxygenLevel":97.5}]`,
+			schemaLegacy, "", []DataEntry{heartRate(1700000000, 72, 97.5)}},
+		{"legacy JS literal",
+			`"[{timestamp: 1700000000, heartRate: 72, bloodOxygenLevel: 97.5}, { timestamp: 1700000060, heartRate: 75, bloodOxygenLevel: 98}]"`,
+			schemaLegacy, "", []DataEntry{heartRate(1700000000, 72, 97.5), heartRate(1700000060, 75, 98)}},
+		{"legacy objects back to back",
+			`"[{timestamp: 1700000000, heartRate: 72, bloodOxygenLevel: 97.5}{timestamp: 1700000060, heartRate: 75, bloodOxygenLevel: 98}]"`,
+			schemaLegacy, "", []DataEntry{heartRate(1700000000, 72, 97.5), heartRate(1700000060, 75, 98)}},
+	} {
+		data, entries, err := decodeAll(tc.payload)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if data.SchemaVersion != tc.version || data.Owner != tc.owner {
+			t.Errorf("%s: version %d, owner %q; want %d, %q", tc.name, data.SchemaVersion, data.Owner, tc.version, tc.owner)
+		}
+		if !reflect.DeepEqual(entries, tc.want) {
+			t.Errorf("%s: entries %v, want %v", tc.name, entries, tc.want)
+		}
+	}
+}
+
+func TestDecodeDatasetRejects(t *testing.T) {
+	const v1Entry = `{"timestamp":1700000000,"heartRate":72,"bloodOxygenLevel":97.5}`
+	const v2Entry = `{"timestamp":1700000000,"metrics":{"heartRate":72}}`
+	for _, tc := range []struct {
+		name, payload, want string
+	}{
+		{"unsupported version", `{"schemaVersion":3,"entries":[]}`, "unsupported schemaVersion 3"},
+		{"v1 missing field", `{"schemaVersion":1,"entries":[{"timestamp":1700000000,"heartRate":72}]}`, "entry 0: missing bloodOxygenLevel"},
+		{"v1 unknown field", `{"schemaVersion":1,"entries":[` + v1Entry + `,{"timestamp":1,"heartRate":1,"bloodOxygenLevel":1,"steps":1}]}`, "unknown field"},
+		{"v2 entries in v1", `{"schemaVersion":1,"entries":[` + v2Entry + `]}`, "unknown field"},
+		{"v1 entries in v2", `{"schemaVersion":2,"entries":[` + v1Entry + `]}`, "unknown field"},
+		{"v2 missing timestamp", `{"schemaVersion":2,"entries":[{"metrics":{"heartRate":72}}]}`, "entry 0: missing timestamp"},
+		{"v2 no metrics", `{"schemaVersion":2,"entries":[{"timestamp":1700000000,"metrics":{}}]}`, "entry 0: no metrics"},
+		{"v2 unknown metric", `{"schemaVersion":2,"entries":[{"timestamp":1700000000,"metrics":{"mood":3}}]}`, `unknown metric "mood"`},
+		{"v2 bad value", `{"schemaVersion":2,"entries":[` + v2Entry + `,{"timestamp":1700000060,"metrics":{"heartRate":"fast"}}]}`, "entry 1: heartRate: want a number"},
+		{"unknown member", `{"schemaVersion":2,"entries":[],"patient":"x"}`, `unknown member "patient"`},
+		{"duplicate member", `{"schemaVersion":2,"entries":[],"entries":[]}`, `duplicate member "entries"`},
+		{"repeated version", `{"schemaVersion":2,"schemaVersion":1,"entries":[]}`, `duplicate member "schemaVersion"`},
+		{"missing entries", `{"schemaVersion":2,"owner":"0xabc"}`, "missing entries"},
+		{"entries not an array", `{"schemaVersion":2,"entries":{}}`, "expected ["},
+		{"data after the document", `{"schemaVersion":2,"entries":[]} {}`, "data after the document"},
+		{"legacy not JSON", `hello`, "invalid character"},
+		{"legacy not an array", `"hello"`, "expected ["},
+		{"legacy cut off", `[` + v1Entry, "parse dataset"},
+		{"legacy empty", ``, "parse dataset"},
+		{"legacy data after the entries", `[` + v1Entry + `] []`, "data after the entries"},
+	} {
+		_, _, err := decodeAll(tc.payload)
+		if err == nil || isTransient(err) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want a permanent error containing %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+// TestDecodeDatasetReadError checks that a read failing part way, as when
+// the streaming envelope is cut off, is passed through to be retried.
+func TestDecodeDatasetReadError(t *testing.T) {
+	// longer than schemaPeek, so the error comes after some entries
+	const entries = 10
+	r := io.MultiReader(
+		strings.NewReader(`{"schemaVersion":2,"entries":[`),
+		strings.NewReader(strings.Repeat(`{"timestamp":1700000000,"metrics":{"heartRate":72}},`, entries)),
+		iotest.ErrReader(io.ErrUnexpectedEOF),
+	)
+	n := 0
+	_, err := decodeDataset(r, func(DataEntry) { n++ })
+	if !errors.Is(err, io.ErrUnexpectedEOF) || !isTransient(classifyErr("parse dataset", err)) {
+		t.Fatalf("got %v, want a transient %v", err, io.ErrUnexpectedEOF)
+	}
+	if n != entries {
+		t.Errorf("yielded %d entries before the error, want %d", n, entries)
+	}
+}
@@ -0,0 +1,52 @@
+#!/bin/sh
+# Checks that the generated HealthTrust binding still matches the Solidity
+# source. It compiles ../Contracts with Hardhat, compares the artifact's ABI
+# with HealthTrust.abi.json, and regenerates healthtrust.go from it.
+#
+#   scripts/check-bindings.sh           fail if anything drifted
+#   scripts/check-bindings.sh --update  rewrite the ABI and binding instead
+set -eu
+
+cd "$(dirname "$0")/.."
+update=false
+[ "${1:-}" = "--update" ] && update=true
+
+artifact=../Contracts/artifacts/contracts/HealthTrust_contract.sol/HealthTrust.json
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+(cd ../Contracts && npx hardhat compile --quiet)
+
+# Key order and whitespace differ between tools, so both sides are
+# normalised before comparing.
+canon='
+const fs = require("fs");
+const sort = (v) => Array.isArray(v) ? v.map(sort)
+  : v && typeof v === "object"
+    ? Object.fromEntries(Object.keys(v).sort().map((k) => [k, sort(v[k])]))
+    : v;
+const j = JSON.parse(fs.readFileSync(process.argv[1], "utf8"));
+process.stdout.write(JSON.stringify(sort(j.abi ?? j), null, 2) + "\n");
+'
+node -e "$canon" "$artifact" > "$tmp/HealthTrust.abi.json"
+
+if $update; then
+	cp "$tmp/HealthTrust.abi.json" HealthTrust.abi.json
+	go generate ./...
+	echo "Updated HealthTrust.abi.json and healthtrust.go"
+	exit 0
+fi
+
+node -e "$canon" HealthTrust.abi.json > "$tmp/current.abi.json"
+if ! diff -u "$tmp/current.abi.json" "$tmp/HealthTrust.abi.json"; then
+	echo "HealthTrust.abi.json does not match HealthTrust_contract.sol; run 'make bindings'" >&2
+	exit 1
+fi
+
+go run github.com/ethereum/go-ethereum/cmd/abigen \
+	--abi HealthTrust.abi.json --pkg main --type HealthTrust --out "$tmp/healthtrust.go"
+if ! diff -q healthtrust.go "$tmp/healthtrust.go" >/dev/null; then
+	echo "healthtrust.go is stale; run 'make bindings'" >&2
+	exit 1
+fi
+echo "HealthTrust binding is up to date"
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Signer signs transactions for the single account the worker sends from.
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// newSigner picks the signing backend from SIGNER:
+//   - "env" (the default) reads a hex key from PRIVATE_KEY
+//   - "keystore" decrypts the go-ethereum keystore file at KEYSTORE_FILE with
+//     the passphrase stored in KEYSTORE_PASSWORD_FILE
+//   - "remote" asks the eth_signTransaction endpoint at SIGNER_URL, such as
+//     Clef or Web3Signer, to sign for SIGNER_ADDRESS
+func newSigner(ctx context.Context) (Signer, error) {
+	switch backend := envOr("SIGNER", "env"); backend {
+	case "env":
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(os.Getenv("PRIVATE_KEY"), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid PRIVATE_KEY: %v", err)
+		}
+		return newKeySigner(key), nil
+	case "keystore":
+		return newKeystoreSigner(os.Getenv("KEYSTORE_FILE"), os.Getenv("KEYSTORE_PASSWORD_FILE"))
+	case "remote":
+		return newRemoteSigner(ctx, os.Getenv("SIGNER_URL"), os.Getenv("SIGNER_ADDRESS"))
+	default:
+		return nil, fmt.Errorf("unknown SIGNER %q", backend)
+	}
+}
+
+// keySigner signs with a private key held in memory.
+type keySigner struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+}
+
+func newKeySigner(key *ecdsa.PrivateKey) *keySigner {
+	return &keySigner{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+func (s *keySigner) Address() common.Address { return s.addr }
+
+func (s *keySigner) SignTx(_ context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// newKeystoreSigner decrypts an encrypted keystore file. The passphrase is
+// read from a file, typically a mounted secret, so neither the key nor the
+// passphrase has to pass through the environment.
+func newKeystoreSigner(keyFile, passwordFile string) (*keySigner, error) {
+	if keyFile == "" || passwordFile == "" {
+		return nil, fmt.Errorf("SIGNER=keystore needs KEYSTORE_FILE and KEYSTORE_PASSWORD_FILE")
+	}
+	keyJSON, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %v", err)
+	}
+	password, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore passphrase: %v", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, strings.TrimRight(string(password), "\r\n"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore %s: %v", keyFile, err)
+	}
+	return newKeySigner(key.PrivateKey), nil
+}
+
+// remoteSigner delegates signing to an external service speaking the
+// eth_signTransaction JSON-RPC method; the key never enters this process.
+type remoteSigner struct {
+	rpc  *rpc.Client
+	addr common.Address
+}
+
+// newRemoteSigner connects to the signer at url. Without an address, the
+// first account the signer reports through eth_accounts is used.
+func newRemoteSigner(ctx context.Context, url, address string) (*remoteSigner, error) {
+	if url == "" {
+		return nil, fmt.Errorf("SIGNER=remote needs SIGNER_URL")
+	}
+	cli, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial signer %s: %v", url, err)
+	}
+
+	s := &remoteSigner{rpc: cli}
+	if address != "" {
+		if !common.IsHexAddress(address) {
+			cli.Close()
+			return nil, fmt.Errorf("invalid SIGNER_ADDRESS %q", address)
+		}
+		s.addr = common.HexToAddress(address)
+		return s, nil
+	}
+
+	var accounts []common.Address
+	if err := cli.CallContext(ctx, &accounts, "eth_accounts"); err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("failed to list signer accounts: %v", err)
+	}
+	if len(accounts) == 0 {
+		cli.Close()
+		return nil, fmt.Errorf("signer %s has no accounts", url)
+	}
+	s.addr = accounts[0]
+	return s, nil
+}
+
+func (s *remoteSigner) Address() common.Address { return s.addr }
+
+// signTxArgs is the transaction object eth_signTransaction takes.
+type signTxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+func (s *remoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := signTxArgs{
+		From:    s.addr,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+
+	var res json.RawMessage
+	if err := s.rpc.CallContext(ctx, &res, "eth_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("remote signer: %v", err)
+	}
+	raw, err := decodeSignResult(res)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid transaction: %v", err)
+	}
+	// Never broadcast something other than what we asked for.
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid signature: %v", err)
+	}
+	if from != s.addr || !sameTx(signed, tx) {
+		return nil, fmt.Errorf("remote signer returned a different transaction than requested")
+	}
+	return signed, nil
+}
+
+// sameTx reports whether signed is the unsigned transaction tx, fees and
+// type included, so a signer cannot raise what we pay.
+func sameTx(signed, tx *types.Transaction) bool {
+	if signed.Type() != tx.Type() || signed.Nonce() != tx.Nonce() || signed.Gas() != tx.Gas() ||
+		signed.To() == nil || tx.To() == nil || *signed.To() != *tx.To() ||
+		signed.Value().Cmp(tx.Value()) != 0 || !bytes.Equal(signed.Data(), tx.Data()) ||
+		len(signed.AccessList()) != len(tx.AccessList()) {
+		return false
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		return signed.GasFeeCap().Cmp(tx.GasFeeCap()) == 0 && signed.GasTipCap().Cmp(tx.GasTipCap()) == 0
+	}
+	return signed.GasPrice().Cmp(tx.GasPrice()) == 0
+}
+
+// decodeSignResult accepts both result shapes in use: geth and Clef return
+// {"raw": ..., "tx": ...}, Web3Signer returns the raw transaction as a string.
+func decodeSignResult(res json.RawMessage) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(res, &raw); err == nil {
+		return raw, nil
+	}
+	var obj struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(res, &obj); err != nil || len(obj.Raw) == 0 {
+		return nil, fmt.Errorf("unexpected remote signer result %s", res)
+	}
+	return obj.Raw, nil
+}
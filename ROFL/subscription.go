@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	minRedialBackoff = 1 * time.Second
+	maxRedialBackoff = 1 * time.Minute
+)
+
+// Ingestion modes: a websocket subscription, or eth_getLogs polling for RPC
+// endpoints that only speak HTTP.
+const (
+	ingestWebsocket = "ws"
+	ingestPoll      = "poll"
+)
+
+// watcherConfig selects how orderWatcher reads logs from the chain.
+type watcherConfig struct {
+	Mode          string        // ingestWebsocket or ingestPoll
+	Confirmations uint64        // blocks on top of a log before it is delivered
+	PollInterval  time.Duration // time between polls in ingestPoll mode
+	LogRange      uint64        // max blocks per eth_getLogs call
+}
+
+// orderWatcher keeps log ingestion alive across connection drops and
+// restarts, and makes sure every log matching query reaches handler exactly
+// once, in (block, index) order, after it has the configured number of
+// confirmations. In websocket mode, logs removed by a reorg after they were
+// handed over are passed to handler again with Removed set; polling cannot
+// see those, and relies on confirmations instead.
+type orderWatcher struct {
+	query      ethereum.FilterQuery
+	handler    func(types.Log)
+	checkpoint *checkpoint
+	cfg        watcherConfig
+
+	// position of the next log we expect; anything before it has already
+	// been handed to handler
+	nextBlock uint64
+	nextIndex uint
+	// last block persisted to the checkpoint
+	savedBlock uint64
+
+	// latest known head and the logs still waiting for confirmations,
+	// sorted by (block, index)
+	head    uint64
+	pending []types.Log
+}
+
+// newOrderWatcher resumes from cp when it holds a block; otherwise the
+// watcher starts at the chain head on its first connection.
+func newOrderWatcher(q ethereum.FilterQuery, cp *checkpoint, cfg watcherConfig, handler func(types.Log)) (*orderWatcher, error) {
+	switch cfg.Mode {
+	case ingestWebsocket, ingestPoll:
+	default:
+		return nil, fmt.Errorf("unknown ingestion mode %q", cfg.Mode)
+	}
+	cfg.LogRange = max(cfg.LogRange, 1)
+	w := &orderWatcher{query: q, handler: handler, checkpoint: cp, cfg: cfg}
+
+	block, ok, err := cp.Load()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		w.nextBlock, w.savedBlock = block+1, block
+		slog.Info("Resuming from checkpoint", "block", block)
+	}
+	return w, nil
+}
+
+// run reads logs in the configured mode and, whenever the connection fails,
+// redials with exponential backoff and backfills the gap. It only returns
+// once ctx is cancelled.
+func (w *orderWatcher) run(ctx context.Context) error {
+	session := w.subscribe
+	if w.cfg.Mode == ingestPoll {
+		session = w.poll
+	}
+
+	backoff := minRedialBackoff
+	for {
+		healthy, err := session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if healthy {
+			backoff = minRedialBackoff
+		}
+		slog.Warn("Log ingestion failed, reconnecting", "in", backoff, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRedialBackoff)
+	}
+}
+
+// subscribe runs a single websocket connection until it fails. subscribed
+// reports whether the subscription was established, so run can reset its
+// backoff after a healthy connection.
+func (w *orderWatcher) subscribe(ctx context.Context) (subscribed bool, err error) {
+	cli, err := ethclient.DialContext(ctx, WS_URL)
+	if err != nil {
+		return false, fmt.Errorf("failed to dial %s: %v", WS_URL, err)
+	}
+	defer cli.Close()
+
+	logs := make(chan types.Log)
+	sub, err := cli.SubscribeFilterLogs(ctx, w.query, logs)
+	if err != nil {
+		return false, fmt.Errorf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// New heads are only needed to count confirmations; a nil channel and
+	// error channel never fire in the select below.
+	var heads chan *types.Header
+	var headErr <-chan error
+	if w.cfg.Confirmations > 0 {
+		heads = make(chan *types.Header)
+		hsub, err := cli.SubscribeNewHead(ctx, heads)
+		if err != nil {
+			return false, fmt.Errorf("failed to subscribe to new heads: %v", err)
+		}
+		defer hsub.Unsubscribe()
+		headErr = hsub.Err()
+	}
+
+	// Subscribing before reading the head means nothing can slip between the
+	// backfill and the live stream; duplicates are dropped by accept.
+	head, err := cli.BlockNumber(ctx)
+	if err != nil {
+		return true, fmt.Errorf("failed to get block number: %v", err)
+	}
+	w.head = max(w.head, head)
+	if w.nextBlock == 0 {
+		w.nextBlock = head + 1
+		w.commit()
+	} else if err := w.backfill(ctx, cli, head); err != nil {
+		return true, err
+	}
+
+	slog.Info("Listening for events", "fromBlock", w.nextBlock, "confirmations", w.cfg.Confirmations)
+	for {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case err := <-sub.Err():
+			return true, fmt.Errorf("subscription dropped: %v", err)
+		case err := <-headErr:
+			return true, fmt.Errorf("head subscription dropped: %v", err)
+		case vLog := <-logs:
+			w.accept(vLog)
+		case h := <-heads:
+			w.head = max(w.head, h.Number.Uint64())
+			w.release()
+		}
+	}
+}
+
+// poll reads logs over HTTP with eth_getLogs every PollInterval until a call
+// fails. polled reports whether at least one poll succeeded, so run can
+// reset its backoff after a healthy connection.
+func (w *orderWatcher) poll(ctx context.Context) (polled bool, err error) {
+	cli, err := ethclient.DialContext(ctx, RPC_URL)
+	if err != nil {
+		return false, fmt.Errorf("failed to dial %s: %v", RPC_URL, err)
+	}
+	defer cli.Close()
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		head, err := cli.BlockNumber(ctx)
+		if err != nil {
+			return polled, fmt.Errorf("failed to get block number: %v", err)
+		}
+		w.head = max(w.head, head)
+		if w.nextBlock == 0 {
+			w.nextBlock = head + 1
+			w.commit()
+		} else if err := w.backfill(ctx, cli, head); err != nil {
+			return polled, err
+		}
+
+		if !polled {
+			slog.Info("Polling for events", "fromBlock", w.nextBlock, "interval", w.cfg.PollInterval, "confirmations", w.cfg.Confirmations)
+			polled = true
+		}
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// backfill replays the logs emitted between the last delivered log and head.
+// Logs still awaiting confirmations are dropped first and re-read from the
+// chain, so any that were reorged out while we were away are forgotten.
+func (w *orderWatcher) backfill(ctx context.Context, cli *ethclient.Client, head uint64) error {
+	w.pending = nil
+	for from := w.nextBlock; from <= head; from += w.cfg.LogRange {
+		to := min(from+w.cfg.LogRange-1, head)
+		if head-w.nextBlock >= w.cfg.LogRange {
+			// only worth reporting when catching up, not on every poll
+			slog.Info("Backfilling logs", "fromBlock", from, "toBlock", to)
+		}
+
+		q := w.query
+		q.FromBlock = new(big.Int).SetUint64(from)
+		q.ToBlock = new(big.Int).SetUint64(to)
+		logs, err := cli.FilterLogs(ctx, q)
+		if err != nil {
+			return fmt.Errorf("failed to backfill blocks %d-%d: %v", from, to, err)
+		}
+		for _, vLog := range logs {
+			w.accept(vLog)
+		}
+	}
+
+	// Everything up to the confirmed head has been delivered; later logs
+	// are in pending.
+	if head >= w.cfg.Confirmations {
+		if confirmed := head - w.cfg.Confirmations; confirmed+1 > w.nextBlock {
+			w.nextBlock, w.nextIndex = confirmed+1, 0
+		}
+	}
+	w.commit()
+	return nil
+}
+
+// accept routes a log from the chain: removals cancel it, confirmed logs are
+// delivered and the rest wait in pending.
+func (w *orderWatcher) accept(vLog types.Log) {
+	if vLog.Removed {
+		w.remove(vLog)
+		return
+	}
+	if w.delivered(vLog) {
+		return
+	}
+
+	i := sort.Search(len(w.pending), func(i int) bool { return !logBefore(w.pending[i], vLog) })
+	if i < len(w.pending) && !logBefore(vLog, w.pending[i]) {
+		return // already pending
+	}
+	w.pending = slices.Insert(w.pending, i, vLog)
+	w.release()
+}
+
+// remove handles a log that a reorg took out of the chain. A reorg removes
+// its logs one after another in chain order, and rewinding for the first
+// would make the rest look undelivered, so every removal that was not
+// pending reaches handler; it ignores logs that never created a job.
+func (w *orderWatcher) remove(vLog types.Log) {
+	for i, p := range w.pending {
+		if p.BlockHash == vLog.BlockHash && p.Index == vLog.Index {
+			slog.Info("Dropping unconfirmed log removed by reorg", "tx", vLog.TxHash.Hex(), "index", vLog.Index)
+			w.pending = slices.Delete(w.pending, i, i+1)
+			return
+		}
+	}
+	w.handler(vLog)
+	if w.delivered(vLog) {
+		// The transaction may be re-included at or after the removed
+		// position; rewind so it is not mistaken for a duplicate. Logs
+		// delivered twice this way are dropped by the job store.
+		w.nextBlock, w.nextIndex = vLog.BlockNumber, vLog.Index
+	}
+}
+
+// release delivers the pending logs that now have enough confirmations.
+func (w *orderWatcher) release() {
+	n := 0
+	for ; n < len(w.pending); n++ {
+		if w.pending[n].BlockNumber+w.cfg.Confirmations > w.head {
+			break
+		}
+		w.deliver(w.pending[n])
+	}
+	w.pending = w.pending[n:]
+}
+
+// delivered reports whether vLog is behind the delivery position.
+func (w *orderWatcher) delivered(vLog types.Log) bool {
+	return vLog.BlockNumber < w.nextBlock ||
+		(vLog.BlockNumber == w.nextBlock && vLog.Index < w.nextIndex)
+}
+
+// deliver hands vLog to the handler unless it has been delivered already.
+func (w *orderWatcher) deliver(vLog types.Log) {
+	if w.delivered(vLog) {
+		return
+	}
+	w.handler(vLog)
+	w.nextBlock, w.nextIndex = vLog.BlockNumber, vLog.Index+1
+	w.commit()
+}
+
+// commit persists the last block whose logs have all been delivered. A
+// failed write is only logged: the worst case is replaying a few blocks
+// after a restart.
+func (w *orderWatcher) commit() {
+	done := w.nextBlock - 1
+	if done <= w.savedBlock {
+		return
+	}
+	if err := w.checkpoint.Save(done); err != nil {
+		slog.Error("Error saving checkpoint", "block", done, "err", err)
+		return
+	}
+	w.savedBlock = done
+}
+
+// logBefore orders logs by position in the chain.
+func logBefore(a, b types.Log) bool {
+	if a.BlockNumber != b.BlockNumber {
+		return a.BlockNumber < b.BlockNumber
+	}
+	return a.Index < b.Index
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// testWatcher returns a watcher positioned at block 1 that records every log
+// handed to its handler.
+func testWatcher(t *testing.T, confirmations uint64) (*orderWatcher, *[]types.Log) {
+	t.Helper()
+	var got []types.Log
+	w := &orderWatcher{
+		handler:    func(vLog types.Log) { got = append(got, vLog) },
+		checkpoint: newCheckpoint(t.TempDir()),
+		cfg:        watcherConfig{Mode: ingestWebsocket, Confirmations: confirmations},
+		nextBlock:  1,
+	}
+	return w, &got
+}
+
+// testLog is the log at (block, index) of a block identified by fork, so a
+// re-included copy can live in a different block with the same number.
+func testLog(block uint64, index uint, fork byte) types.Log {
+	return types.Log{
+		BlockNumber: block,
+		BlockHash:   common.Hash{fork, byte(block)},
+		TxHash:      common.Hash{0xaa, byte(block), byte(index)},
+		Index:       index,
+	}
+}
+
+func removed(vLog types.Log) types.Log {
+	vLog.Removed = true
+	return vLog
+}
+
+func countRemoved(logs []types.Log) (n int) {
+	for _, l := range logs {
+		if l.Removed {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWatcherDropsPendingLogRemovedByReorg(t *testing.T) {
+	w, got := testWatcher(t, 2)
+	w.head = 10
+
+	log10 := testLog(10, 0, 1)
+	w.accept(log10)
+	w.accept(removed(log10))
+	w.head = 20
+	w.release()
+
+	if len(*got) != 0 {
+		t.Fatalf("handler got %d logs, want none", len(*got))
+	}
+	if len(w.pending) != 0 {
+		t.Fatalf("%d logs still pending", len(w.pending))
+	}
+}
+
+func TestWatcherPassesDeliveredLogRemovedByReorg(t *testing.T) {
+	w, got := testWatcher(t, 2)
+	w.head = 20
+
+	log10 := testLog(10, 0, 1)
+	w.accept(log10)
+	w.accept(removed(log10))
+
+	if len(*got) != 2 || countRemoved(*got) != 1 {
+		t.Fatalf("handler got %v, want the log and its removal", *got)
+	}
+	if w.nextBlock != 10 || w.nextIndex != 0 {
+		t.Fatalf("position %d/%d, want 10/0", w.nextBlock, w.nextIndex)
+	}
+}
+
+func TestWatcherPassesEveryRemovedLogOfAReorg(t *testing.T) {
+	w, got := testWatcher(t, 0)
+	w.head = 20
+
+	logs := []types.Log{testLog(10, 0, 1), testLog(11, 0, 1), testLog(12, 0, 1), testLog(12, 1, 1)}
+	for _, l := range logs {
+		w.accept(l)
+	}
+	// geth sends the removed logs of a reorg in chain order
+	for _, l := range logs {
+		w.accept(removed(l))
+	}
+
+	if n := countRemoved(*got); n != len(logs) {
+		t.Fatalf("handler got %d removals, want %d", n, len(logs))
+	}
+	if w.nextBlock != 10 || w.nextIndex != 0 {
+		t.Fatalf("position %d/%d, want 10/0", w.nextBlock, w.nextIndex)
+	}
+}
+
+func TestWatcherDeliversLogReincludedAfterReorg(t *testing.T) {
+	w, got := testWatcher(t, 1)
+	w.head = 20
+
+	orig := testLog(10, 0, 1)
+	w.accept(orig)
+	w.accept(removed(orig))
+
+	// the same transaction mined again, once in the new block 10 and then
+	// later in block 11
+	again := testLog(10, 0, 2)
+	w.accept(again)
+	later := testLog(11, 0, 2)
+	later.TxHash = orig.TxHash
+	w.accept(later)
+
+	want := []types.Log{orig, removed(orig), again, later}
+	if len(*got) != len(want) {
+		t.Fatalf("handler got %d logs, want %d", len(*got), len(want))
+	}
+	for i, l := range want {
+		if (*got)[i].BlockHash != l.BlockHash || (*got)[i].Removed != l.Removed {
+			t.Errorf("log %d: got %+v, want %+v", i, (*got)[i], l)
+		}
+	}
+}
+
+func TestHandleRemovedCancelsQueuedJob(t *testing.T) {
+	store, err := openJobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevJobs, prevPool := jobs, pool
+	jobs, pool = store, newWorkerPool(1, 4) // not started: jobs stay queued
+	t.Cleanup(func() { jobs, pool = prevJobs, prevPool })
+
+	vLog := testLog(10, 0, 1)
+	job, _, err := jobs.Add(big.NewInt(1), big.NewInt(7), vLog.TxHash.Hex(), vLog.Index, time.Now().Add(orderTTL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Submit(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+
+	handleRemoved(removed(vLog))
+
+	if pool.Depth() != 0 {
+		t.Errorf("job still queued")
+	}
+	got, ok := jobs.ByLog(vLog.TxHash.Hex(), vLog.Index)
+	if ok {
+		t.Errorf("removed log still maps to job %s in state %s", got.Key(), got.State)
+	}
+	if jobs.SeenLog(vLog.TxHash.Hex(), vLog.Index) {
+		t.Errorf("removed log still counts as seen")
+	}
+	if pending := jobs.Pending(); len(pending) != 0 {
+		t.Errorf("%d jobs still pending", len(pending))
+	}
+	// a re-included log creates the job again
+	if _, created, err := jobs.Add(big.NewInt(1), big.NewInt(7), vLog.TxHash.Hex(), vLog.Index, time.Now().Add(orderTTL)); err != nil || !created {
+		t.Errorf("re-adding cancelled job: created %v, err %v", created, err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// tokenMeta is what we need to show an ERC-20 amount to a human.
+type tokenMeta struct {
+	Decimals uint8
+	Symbol   string
+}
+
+var (
+	tokenMu    sync.Mutex
+	tokenCache = map[common.Address]tokenMeta{}
+)
+
+// getTokenMeta reads decimals and symbol from the token contract. Tokens
+// whose decimals() cannot be read fall back to 18 decimals and no symbol.
+// Only a successful read is cached, so a failed RPC call does not leave a
+// token misformatted for the life of the process.
+func getTokenMeta(ctx context.Context, token common.Address) tokenMeta {
+	tokenMu.Lock()
+	meta, ok := tokenCache[token]
+	tokenMu.Unlock()
+	if ok {
+		return meta
+	}
+
+	meta = tokenMeta{Decimals: 18}
+	cli, err := ethclient.Dial(RPC_URL)
+	if err != nil {
+		slog.Warn("Error reading token metadata", "token", token.Hex(), "err", err)
+		return meta
+	}
+	defer cli.Close()
+
+	erc20, err := NewERC20Caller(token, cli)
+	if err != nil {
+		slog.Warn("Error reading token metadata", "token", token.Hex(), "err", err)
+		return meta
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	decimals, err := erc20.Decimals(opts)
+	if err != nil {
+		slog.Warn("Error reading token decimals, assuming 18", "token", token.Hex(), "err", err)
+		return meta
+	}
+	meta.Decimals = decimals
+	if symbol, err := erc20.Symbol(opts); err == nil {
+		meta.Symbol = symbol
+	}
+
+	tokenMu.Lock()
+	tokenCache[token] = meta
+	tokenMu.Unlock()
+	return meta
+}
+
+// formatTokenAmount renders amount base units as a decimal string, e.g.
+// 10000000000000000 with 18 decimals is "0.01". It never rounds.
+func formatTokenAmount(amount *big.Int, decimals uint8) string {
+	if amount == nil {
+		return "0"
+	}
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	digits := new(big.Int).Abs(amount).String()
+	if decimals == 0 {
+		return sign + digits
+	}
+
+	if pad := int(decimals) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	whole, frac := digits[:len(digits)-int(decimals)], strings.TrimRight(digits[len(digits)-int(decimals):], "0")
+	if frac == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + frac
+}
+
+// String formats amount with the token's symbol when it has one.
+func (m tokenMeta) String(amount *big.Int) string {
+	s := formatTokenAmount(amount, m.Decimals)
+	if m.Symbol != "" {
+		s += " " + m.Symbol
+	}
+	return s
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// txService sends every HealthTrust write from the account of its signer.
+// Nonces are allocated locally, so concurrent orders never race on
+// PendingNonceAt: submissions are serialized up to the point the transaction
+// is sent, and waiting for receipts happens in parallel.
+type txService struct {
+	cli      *ethclient.Client
+	contract *bind.BoundContract
+	signer   Signer
+	chainID  *big.Int
+
+	mu      sync.Mutex // held from nonce allocation until the tx is sent
+	nonce   uint64     // next nonce to use, valid when synced
+	synced  bool
+	pending map[uint64]*types.Transaction // latest tx per nonce, waiting for a receipt
+}
+
+func newTxService(ctx context.Context, signer Signer) (*txService, error) {
+	cli, err := ethclient.DialContext(ctx, RPC_URL)
+	if err != nil {
+		return nil, err
+	}
+	chainID, err := cli.ChainID(ctx)
+	if err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("failed to get chain ID: %v", err)
+	}
+	parsed, err := HealthTrustMetaData.GetAbi()
+	if err != nil {
+		cli.Close()
+		return nil, err
+	}
+
+	return &txService{
+		cli:      cli,
+		contract: bind.NewBoundContract(CONTRACT_ADDR, *parsed, cli, cli, cli),
+		signer:   signer,
+		chainID:  chainID,
+		pending:  make(map[uint64]*types.Transaction),
+	}, nil
+}
+
+// Submit calls the HealthTrust method with args in a transaction and waits
+// until it is mined. A transaction still pending after stuckTxTimeout is
+// replaced by the same call at the same nonce with bumped fees, so one
+// underpriced write cannot hold up every later nonce. It fails if the
+// transaction reverts or stays stuck through every replacement; the nonce is
+// then handed to cancel rather than given up while still pending.
+func (s *txService) Submit(ctx context.Context, method string, args ...interface{}) error {
+	fees, err := suggestFees(ctx, s.cli)
+	if err != nil {
+		return err
+	}
+	tx, err := s.send(ctx, nil, fees, method, args...)
+	if err != nil {
+		return err
+	}
+
+	hashes := []common.Hash{tx.Hash()}
+	for bumps := 0; ; bumps++ {
+		receipt, err := waitForReceipt(ctx, s.cli, stuckTxTimeout, hashes...)
+		if err == nil {
+			s.done(tx.Nonce())
+			if receipt.Status == types.ReceiptStatusFailed {
+				return fmt.Errorf("transaction %s (%s) reverted", receipt.TxHash.Hex(), method)
+			}
+			slog.Info("Transaction mined", "tx", receipt.TxHash.Hex(), "method", method, "block", receipt.BlockNumber)
+			return nil
+		}
+		if !errors.Is(err, errReceiptTimeout) {
+			go s.cancel(tx.Nonce(), fees, hashes)
+			return fmt.Errorf("failed to get receipt of %s: %v", tx.Hash().Hex(), err)
+		}
+		if bumps >= maxFeeBumps {
+			go s.cancel(tx.Nonce(), fees, hashes)
+			return transientErr("submit "+method, fmt.Errorf("transaction with nonce %d still pending after %d replacement(s)", tx.Nonce(), bumps))
+		}
+
+		bumped, ok := fees.bump()
+		if !ok {
+			slog.Warn("Transaction pending with fees already at the configured cap", "tx", tx.Hash().Hex(), "method", method, "pendingFor", stuckTxTimeout)
+			continue
+		}
+		nonce := tx.Nonce()
+		replacement, err := s.send(ctx, &nonce, bumped, method, args...)
+		if err != nil {
+			// "nonce too low" means one of the earlier attempts was just
+			// mined; the next wait picks up its receipt.
+			slog.Warn("Error replacing transaction", "tx", tx.Hash().Hex(), "err", err)
+			continue
+		}
+		slog.Info("Replaced stuck transaction", "tx", tx.Hash().Hex(), "replacement", replacement.Hash().Hex(), "fees", bumped)
+		tx, fees = replacement, bumped
+		hashes = append(hashes, tx.Hash())
+	}
+}
+
+// send signs and broadcasts the call with fees. A nil nonce allocates the
+// next local nonce; otherwise the transaction replaces the one sent at
+// *nonce.
+func (s *txService) send(ctx context.Context, nonce *uint64, fees txFees, method string, args ...interface{}) (*types.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if nonce == nil && !s.synced {
+		next, err := s.cli.PendingNonceAt(ctx, s.signer.Address())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get nonce: %v", err)
+		}
+		s.nonce, s.synced = next, true
+	}
+	use := s.nonce
+	if nonce != nil {
+		use = *nonce
+	}
+
+	opts := &bind.TransactOpts{
+		From: s.signer.Address(),
+		Signer: func(_ common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			return s.signer.SignTx(ctx, tx, s.chainID)
+		},
+		Context: ctx,
+		Nonce:   new(big.Int).SetUint64(use),
+	}
+	fees.apply(opts)
+
+	tx, err := s.contract.Transact(opts, method, args...)
+	if err != nil {
+		// Someone else may have used our key, or a previous tx was
+		// dropped; ask the node again next time.
+		if msg := strings.ToLower(err.Error()); nonce == nil && strings.Contains(msg, "nonce") {
+			s.synced = false
+		}
+		return nil, fmt.Errorf("failed to send %s: %v", method, err)
+	}
+
+	if nonce == nil {
+		s.nonce++
+	}
+	s.pending[use] = tx
+	slog.Info("Transaction sent", "tx", tx.Hash().Hex(), "method", method, "nonce", use, "fees", fees, "pending", len(s.pending))
+	return tx, nil
+}
+
+// cancel takes over a nonce whose call Submit gave up on while it was still
+// pending. Every later nonce waits for it, so it is not released until
+// something is mined at it: the call is replaced by a 0-value transfer to
+// ourselves, re-sent every stuckTxTimeout with fees bumped as far as the caps
+// allow, which also fills the gap if the node dropped the call. The order
+// is retried at a later nonce and finds out on chain whether the abandoned
+// call went through after all.
+func (s *txService) cancel(nonce uint64, fees txFees, hashes []common.Hash) {
+	ctx := context.Background()
+	slog.Warn("Cancelling stuck transaction", "nonce", nonce, "tx", hashes[len(hashes)-1].Hex())
+	for {
+		if bumped, ok := fees.bump(); ok {
+			fees = bumped
+		}
+		if tx, err := s.sendCancel(ctx, nonce, fees); err != nil {
+			// "nonce too low" means something was mined at the nonce;
+			// the check below picks that up.
+			slog.Warn("Error sending cancellation", "nonce", nonce, "err", err)
+		} else if !slices.Contains(hashes, tx.Hash()) {
+			hashes = append(hashes, tx.Hash())
+		}
+
+		receipt, err := waitForReceipt(ctx, s.cli, stuckTxTimeout, hashes...)
+		if err == nil {
+			slog.Info("Stuck nonce released", "nonce", nonce, "tx", receipt.TxHash.Hex())
+			s.done(nonce)
+			return
+		}
+		// Someone else's transaction at the nonce, e.g. one sent with our
+		// key elsewhere, releases it as well.
+		if mined, nerr := s.cli.NonceAt(ctx, s.signer.Address(), nil); nerr == nil && mined > nonce {
+			slog.Info("Stuck nonce released", "nonce", nonce)
+			s.done(nonce)
+			return
+		}
+		if !errors.Is(err, errReceiptTimeout) {
+			slog.Warn("Error waiting for cancellation", "nonce", nonce, "err", err)
+			time.Sleep(stuckTxTimeout)
+		}
+	}
+}
+
+// sendCancel signs and broadcasts a 0-value transfer to ourselves at nonce.
+func (s *txService) sendCancel(ctx context.Context, nonce uint64, fees txFees) (*types.Transaction, error) {
+	to := s.signer.Address()
+	var data types.TxData = &types.LegacyTx{
+		Nonce: nonce, GasPrice: fees.GasPrice, Gas: params.TxGas, To: &to, Value: new(big.Int),
+	}
+	if fees.dynamic() {
+		data = &types.DynamicFeeTx{
+			ChainID: s.chainID, Nonce: nonce, GasTipCap: fees.GasTipCap, GasFeeCap: fees.GasFeeCap,
+			Gas: params.TxGas, To: &to, Value: new(big.Int),
+		}
+	}
+	tx, err := s.signer.SignTx(ctx, types.NewTx(data), s.chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cli.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.pending[nonce] = tx
+	s.mu.Unlock()
+	slog.Info("Cancellation sent", "tx", tx.Hash().Hex(), "nonce", nonce, "fees", fees)
+	return tx, nil
+}
+
+func (s *txService) done(nonce uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, nonce)
+}
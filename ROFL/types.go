@@ -1,21 +1,33 @@
 package main
 
+import "math/big"
+
+// Ids, amounts and timestamps are uint256 / uint40 on chain and are kept as
+// *big.Int so that nothing is truncated on the way through.
+
 type computeReq struct {
-	OrderId   uint64 `json:"orderId"`
-	RAddress  string `json:"raAddress"`
-	DatasetId uint64 `json:"datasetId"`
-	Amount    int64  `json:"amount"`
+	OrderId   *big.Int `json:"orderId"`
+	RAddress  string   `json:"raAddress"`
+	DatasetId *big.Int `json:"datasetId"`
+	Amount    *big.Int `json:"amount"` // token base units
 }
 
+// DataEntry is one timestamped set of measurements, keyed by metric; see
+// metricRegistry.
 type DataEntry struct {
-	Timestamp        int64   `json:"timestamp"`
-	HeartRate        int64   `json:"heartRate"`
-	BloodOxygenLevel float64 `json:"bloodOxygenLevel"`
+	Timestamp int64                      `json:"timestamp"`
+	Values    map[MetricType]metricValue `json:"values"`
 }
 
+// Data describes a dataset payload; see schema.go for how each version is
+// read. The entries are not part of it: decoders hand each one to a
+// callback as it is read, so a dataset is never held in memory whole.
 type Data struct {
-	Owner   string      `json:"owner"`
-	Entries []DataEntry `json:"entries"`
+	SchemaVersion int    `json:"schemaVersion"`
+	Owner         string `json:"owner,omitempty"`
+	// Rejected counts records an importer could not turn into entries, by
+	// reason; see fhir.go.
+	Rejected map[string]int `json:"-"`
 }
 
 type DataResponse struct {
@@ -23,12 +35,12 @@ type DataResponse struct {
 }
 
 type Order struct {
-	OrderId      uint64 `json:"orderId"`
-	DatasetId    uint64 `json:"datasetId"`
-	Researcher   string `json:"researcher"` // the payer
-	Patient      string `json:"patient"`    // dataset owner / payee
-	Amount       uint64 `json:"amount"`
-	TokenAddress string `json:"tokenAddress"`
-	Timestamp    uint64 `json:"timestamp"` // creation time
-	Completed    bool   `json:"completed"`
+	OrderId      *big.Int `json:"orderId"`
+	DatasetId    *big.Int `json:"datasetId"`
+	Researcher   string   `json:"researcher"` // the payer
+	Patient      string   `json:"patient"`    // dataset owner / payee
+	Amount       *big.Int `json:"amount"`     // token base units
+	TokenAddress string   `json:"tokenAddress"`
+	Timestamp    *big.Int `json:"timestamp"` // creation time
+	Completed    bool     `json:"completed"`
 }
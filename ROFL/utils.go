@@ -2,6 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -10,25 +14,65 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
-func waitForReceipt(client *ethclient.Client, txHash common.Hash) (*types.Receipt, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
+// errReceiptTimeout is returned by waitForReceipt when none of the
+// transactions was mined in time.
+var errReceiptTimeout = errors.New("timed out waiting for receipt")
 
-	for {
-		receipt, err := client.TransactionReceipt(ctx, txHash)
-		if err == nil {
-			return receipt, nil
-		}
+// waitForReceipt polls until one of txHashes is mined and returns its
+// receipt. The hashes are usually replacements of each other, so at most one
+// of them can ever be mined. Transient RPC errors are retried until the
+// timeout: callers treat any error as the transaction being stuck, and a
+// single failed poll must not make them give up on it.
+func waitForReceipt(ctx context.Context, client *ethclient.Client, timeout time.Duration, txHashes ...common.Hash) (*types.Receipt, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
 
-		if err != ethereum.NotFound {
-			return nil, err
+	for {
+		for _, txHash := range txHashes {
+			receipt, err := client.TransactionReceipt(ctx, txHash)
+			if err == nil {
+				return receipt, nil
+			}
+			if errors.Is(err, ethereum.NotFound) {
+				continue
+			}
+			if ctx.Err() != nil || !looksTransient(err) {
+				return nil, err
+			}
+			slog.Warn("Error polling for receipt, retrying", "tx", txHash.Hex(), "err", err)
+			break
 		}
 
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
+		case <-deadline.C:
+			return nil, errReceiptTimeout
 		case <-time.After(2 * time.Second):
 			// Wait and retry
 		}
 	}
 }
+
+// envOr returns the environment variable key, or def when it is unset.
+func envOr(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// envInt returns the integer environment variable key, or def when it is
+// unset or not a number.
+func envInt(key string, def int) int {
+	v := envOr(key, "")
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		slog.Warn("Ignoring invalid environment variable", "name", key, "value", v, "err", err)
+		return def
+	}
+	return n
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// receiptNode answers eth_getTransactionReceipt with each of responses in
+// turn: an HTTP status for a failed call, or 0 for the receipt.
+func receiptNode(t *testing.T, receipt *types.Receipt, responses ...int) *ethclient.Client {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_getTransactionReceipt" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		status := responses[min(calls, len(responses)-1)]
+		calls++
+		if status != 0 {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": receipt})
+	}))
+	t.Cleanup(srv.Close)
+	cli, err := ethclient.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cli.Close)
+	return cli
+}
+
+func TestWaitForReceiptRetriesTransientErrors(t *testing.T) {
+	want := &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: common.Hash{1}, Logs: []*types.Log{}}
+	cli := receiptNode(t, want, http.StatusBadGateway, 0)
+
+	got, err := waitForReceipt(context.Background(), cli, time.Minute, want.TxHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TxHash != want.TxHash {
+		t.Errorf("got receipt of %s, want %s", got.TxHash.Hex(), want.TxHash.Hex())
+	}
+}
+
+func TestWaitForReceiptTimesOutThroughTransientErrors(t *testing.T) {
+	cli := receiptNode(t, nil, http.StatusServiceUnavailable)
+
+	_, err := waitForReceipt(context.Background(), cli, 100*time.Millisecond, common.Hash{1})
+	if err != errReceiptTimeout {
+		t.Fatalf("got %v, want %v", err, errReceiptTimeout)
+	}
+}
+
+func TestWaitForReceiptFailsOnPermanentErrors(t *testing.T) {
+	cli := receiptNode(t, nil, http.StatusUnauthorized)
+
+	_, err := waitForReceipt(context.Background(), cli, time.Minute, common.Hash{1})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("got %v, want the node's error", err)
+	}
+}
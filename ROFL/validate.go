@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// metricRange is the physiologically plausible range of a metric. Values
+// outside it are sensor faults or entry errors rather than measurements.
+type metricRange struct {
+	Min, Max float64
+}
+
+func (r metricRange) contains(v float64) bool {
+	return v >= r.Min && v <= r.Max
+}
+
+// Reasons an entry is rejected, as counted in qualityReport.Rejections.
+// A value out of range is counted as "<metric> out of range".
+const (
+	rejectTimestamp  = "invalid timestamp"
+	rejectDuplicate  = "duplicate timestamp"
+	rejectNoDeclared = "no declared metrics"
+)
+
+// qualityReport summarises validation of a dataset. It is pinned with the
+// result so the researcher can see how much of the dataset the aggregate
+// rests on. It holds counts only, never values.
+type qualityReport struct {
+	Total      int            `json:"total"`
+	Accepted   int            `json:"accepted"`
+	Rejected   int            `json:"rejected"`
+	Rejections map[string]int `json:"rejections,omitempty"` // reason -> entries
+	// OutOfOrder counts accepted entries older than the entry before them.
+	// They are kept, but point at a client that does not sort its data.
+	OutOfOrder int `json:"outOfOrder"`
+	// Undeclared counts values of metrics the dataset does not declare.
+	// They are dropped: the researcher only paid for the declared ones.
+	Undeclared map[string]int `json:"undeclared,omitempty"`
+	// Missing lists declared metrics no accepted entry carries.
+	Missing []string `json:"missing,omitempty"`
+}
+
+func (r qualityReport) String() string {
+	reasons := make([]string, 0, len(r.Rejections))
+	for reason, n := range r.Rejections {
+		reasons = append(reasons, fmt.Sprintf("%s: %d", reason, n))
+	}
+	sort.Strings(reasons)
+	s := fmt.Sprintf("%d/%d accepted, %d out of order [%s]", r.Accepted, r.Total, r.OutOfOrder, strings.Join(reasons, ", "))
+	if len(r.Missing) > 0 {
+		s += " missing " + strings.Join(r.Missing, ", ")
+	}
+	return s
+}
+
+// addRejected counts records rejected before validation, by reason.
+func (r *qualityReport) addRejected(rejected map[string]int) {
+	for reason, n := range rejected {
+		r.Total += n
+		r.Rejected += n
+		r.Rejections[reason] += n
+	}
+}
+
+// entryValidator checks entries as they are decoded and aggregates the
+// ones fit to use, keeping only the values of the declared metrics. An entry
+// is rejected for the first rule it breaks; of several entries with the same
+// timestamp only the first is kept. All it holds on to is the set of
+// timestamps seen and the running totals of each metric.
+type entryValidator struct {
+	declared []metricDef
+	// inferred is set when the dataset declares no metrics: every metric
+	// is accepted, as before datasets declared them, and none is missing.
+	inferred bool
+	report   qualityReport
+	seen     map[int64]bool
+	last     int64
+	stats    map[MetricType]*metricStats // accepted values, by metric
+}
+
+func newEntryValidator(declared []metricDef) *entryValidator {
+	v := &entryValidator{
+		declared: declared,
+		report: qualityReport{
+			Rejections: make(map[string]int),
+			Undeclared: make(map[string]int),
+		},
+		seen:  make(map[int64]bool),
+		stats: make(map[MetricType]*metricStats),
+	}
+	if len(declared) == 0 {
+		v.declared, v.inferred = allMetrics(), true
+	}
+	return v
+}
+
+// add validates e and, if it is accepted, adds its values to the totals.
+func (v *entryValidator) add(e DataEntry) {
+	v.report.Total++
+	kept := 0
+	for t := range e.Values {
+		if !slices.ContainsFunc(v.declared, func(d metricDef) bool { return d.Type == t }) {
+			v.report.Undeclared[metricRegistry[t].Name]++
+			continue
+		}
+		kept++
+	}
+
+	reason := ""
+	switch {
+	case e.Timestamp <= 0:
+		reason = rejectTimestamp
+	case v.seen[e.Timestamp]:
+		reason = rejectDuplicate
+	case kept == 0:
+		reason = rejectNoDeclared
+	default:
+		for _, def := range v.declared {
+			if x, ok := e.Values[def.Type]; ok && !def.valid(x) {
+				reason = def.Name + " out of range"
+				break
+			}
+		}
+	}
+	if reason != "" {
+		v.report.Rejected++
+		v.report.Rejections[reason]++
+		return
+	}
+
+	if v.report.Accepted > 0 && e.Timestamp < v.last {
+		v.report.OutOfOrder++
+	}
+	v.report.Accepted++
+	v.seen[e.Timestamp] = true
+	v.last = e.Timestamp
+	for _, def := range v.declared {
+		x, ok := e.Values[def.Type]
+		if !ok {
+			continue
+		}
+		s, ok := v.stats[def.Type]
+		if !ok {
+			s = newMetricStats(def)
+			v.stats[def.Type] = s
+		}
+		s.add(x)
+	}
+}
+
+// finish adds the records rejected before validation and returns the
+// report.
+func (v *entryValidator) finish(rejected map[string]int) qualityReport {
+	v.report.addRejected(rejected)
+	if !v.inferred {
+		for _, def := range v.declared {
+			if _, ok := v.stats[def.Type]; !ok {
+				v.report.Missing = append(v.report.Missing, def.Name)
+			}
+		}
+	}
+	return v.report
+}
+
+// summaries returns the aggregate of each metric any accepted entry carries,
+// by name.
+func (v *entryValidator) summaries() map[string]metricSummary {
+	out := make(map[string]metricSummary, len(v.stats))
+	for _, s := range v.stats {
+		out[s.def.Name] = s.summary()
+	}
+	return out
+}
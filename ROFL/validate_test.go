@@ -0,0 +1,72 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// validatePayload streams a JSON payload through an entryValidator.
+func validatePayload(t *testing.T, payload string, declared []metricDef) (*entryValidator, qualityReport) {
+	t.Helper()
+	v := newEntryValidator(declared)
+	data, err := decodePayload(contentJSON, strings.NewReader(payload), v.add)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v, v.finish(data.Rejected)
+}
+
+func TestEntryValidator(t *testing.T) {
+	payload := `{"schemaVersion":2,"entries":[
+		{"timestamp":1700000060,"metrics":{"heartRate":70,"steps":120}},
+		{"timestamp":1700000000,"metrics":{"heartRate":80}},
+		{"timestamp":1700000000,"metrics":{"heartRate":90}},
+		{"timestamp":1700000120,"metrics":{"heartRate":400}},
+		{"timestamp":1700000180,"metrics":{"steps":50}},
+		{"timestamp":1700000240,"metrics":{"heartRate":60,"sleepStage":"deep"}}
+	]}`
+	declared := []metricDef{metricsByName["heartRate"], metricsByName["bloodOxygenLevel"]}
+	v, report := validatePayload(t, payload, declared)
+
+	if report.Total != 6 || report.Accepted != 3 || report.Rejected != 3 || report.OutOfOrder != 1 {
+		t.Errorf("report %s", report)
+	}
+	for reason, n := range map[string]int{rejectDuplicate: 1, "heartRate out of range": 1, rejectNoDeclared: 1} {
+		if report.Rejections[reason] != n {
+			t.Errorf("%d rejected for %q, want %d", report.Rejections[reason], reason, n)
+		}
+	}
+	if report.Undeclared["steps"] != 2 || report.Undeclared["sleepStage"] != 1 {
+		t.Errorf("undeclared %v", report.Undeclared)
+	}
+	if !slices.Equal(report.Missing, []string{"bloodOxygenLevel"}) {
+		t.Errorf("missing %v", report.Missing)
+	}
+
+	summaries := v.summaries()
+	hr, ok := summaries["heartRate"]
+	if len(summaries) != 1 || !ok {
+		t.Fatalf("summaries %v", summaries)
+	}
+	if hr.Count != 3 || hr.Mean != 70.0 || hr.Min != 60.0 || hr.Max != 80.0 {
+		t.Errorf("heartRate summary %+v", hr)
+	}
+}
+
+func TestEntryValidatorWithoutDeclaredMetrics(t *testing.T) {
+	payload := `[{"timestamp":1700000000,"heartRate":72,"bloodOxygenLevel":97.5},
+		{"timestamp":1700000060,"heartRate":400,"bloodOxygenLevel":97}]`
+	v, report := validatePayload(t, payload, nil)
+
+	if report.Accepted != 1 || report.Rejections["heartRate out of range"] != 1 {
+		t.Errorf("report %s", report)
+	}
+	if len(report.Undeclared) != 0 || len(report.Missing) != 0 {
+		t.Errorf("undeclared %v, missing %v", report.Undeclared, report.Missing)
+	}
+	summaries := v.summaries()
+	if len(summaries) != 2 || summaries["heartRate"].Count != 1 || summaries["bloodOxygenLevel"].Mean != 97.5 {
+		t.Errorf("summaries %+v", summaries)
+	}
+}